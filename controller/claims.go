@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/validation"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cryptUIDIndex indexes the secrets of the controller by the UID of the Crypt they were created for. secrets created
+// by earlier versions of the controller are indexed by the UID in their owner reference.
+const cryptUIDIndex = "cryptUID"
+
+func indexByCryptUID(obj interface{}) ([]string, error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return nil, nil
+	}
+
+	var uids []string
+	if secret.Labels[ManagedByLabel] == ComponentName && secret.Labels[CryptUIDLabel] != "" {
+		uids = append(uids, secret.Labels[CryptUIDLabel])
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == "Crypt" && (len(uids) == 0 || uids[0] != string(ref.UID)) {
+			uids = append(uids, string(ref.UID))
+		}
+	}
+	return uids, nil
+}
+
+// precedes tells whether crypt a wins over crypt b for the secrets they both define in a namespace. the oldest crypt
+// wins, and crypts created in the same second are ordered by namespace and name, so every worker picks the same one.
+func precedes(a, b *v1alpha1.Crypt) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// claims maps the namespace/name of secrets to the Crypt that takes precedence for them.
+type claims map[string]*v1alpha1.Crypt
+
+// claimant is what a Crypt claims according to its spec, which is kept until the spec of the Crypt changes, rather
+// than worked out again every time another Crypt is synced or a namespace changes.
+type claimant struct {
+	uid        types.UID
+	generation int64
+	// matcher is nil for a crypt with an invalid spec, which does not write secrets and so claims none
+	matcher *namespaceMatcher
+	names   map[string]struct{}
+	// prefixed tells whether the crypt also claims the secrets it created for the keys under a prefix
+	prefixed bool
+}
+
+// claimantFor returns what a Crypt claims according to its current spec.
+func (c *Controller) claimantFor(key string, crypt *v1alpha1.Crypt) *claimant {
+	c.claimantsMu.Lock()
+	defer c.claimantsMu.Unlock()
+
+	if cl, ok := c.claimants[key]; ok && cl.uid == crypt.UID && cl.generation == crypt.Generation {
+		return cl
+	}
+
+	cl := &claimant{
+		uid:        crypt.UID,
+		generation: crypt.Generation,
+		names:      make(map[string]struct{}),
+	}
+	if len(validation.ValidateCrypt(crypt)) == 0 {
+		if matcher, err := newNamespaceMatcher(crypt); err == nil {
+			cl.matcher = matcher
+		}
+	}
+	for _, sec := range crypt.Spec.Secrets {
+		if sec.GetPrefix() != "" {
+			cl.prefixed = true
+			continue
+		}
+		cl.names[sec.GetName()] = struct{}{}
+	}
+
+	c.claimants[key] = cl
+	return cl
+}
+
+func (c *Controller) forgetClaimant(key string) {
+	c.claimantsMu.Lock()
+	delete(c.claimants, key)
+	c.claimantsMu.Unlock()
+}
+
+// precedingClaims returns the secrets that Crypts taking precedence over crypt define in any of the given namespaces.
+// crypts being deleted or with an invalid spec do not write secrets, and so claim none.
+func (c *Controller) precedingClaims(crypt *v1alpha1.Crypt, namespaces []string) (claims, error) {
+	crypts, err := c.cryptLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(claims)
+	for _, other := range crypts {
+		if other.Namespace == crypt.Namespace && other.Name == crypt.Name {
+			continue
+		}
+		if other.DeletionTimestamp != nil || !precedes(other, crypt) {
+			continue
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(other)
+		if err != nil {
+			continue
+		}
+		cl := c.claimantFor(key, other)
+		if cl.matcher == nil {
+			continue
+		}
+		names := cl.names
+		if cl.prefixed {
+			names = c.prefixedNames(other, names)
+		}
+
+		for _, ns := range namespaces {
+			namespace, err := c.namespaceLister.Get(ns)
+			if err != nil || !cl.matcher.matches(namespace) {
+				continue
+			}
+			for name := range names {
+				key := ns + "/" + name
+				if winner, ok := result[key]; !ok || precedes(other, winner) {
+					result[key] = other
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// prefixedNames adds the names of the secrets a Crypt created from a prefix to the names it defines. those are only
+// known from the secrets the Crypt already owns.
+func (c *Controller) prefixedNames(crypt *v1alpha1.Crypt, defined map[string]struct{}) map[string]struct{} {
+	objs, err := c.secretIndexer.ByIndex(cryptUIDIndex, string(crypt.UID))
+	if err != nil {
+		return defined
+	}
+
+	names := make(map[string]struct{}, len(defined)+len(objs))
+	for name := range defined {
+		names[name] = struct{}{}
+	}
+	for _, obj := range objs {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			continue
+		}
+		if _, ok := secret.Annotations[PrefixAnnotation]; ok {
+			names[secret.Name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// enqueueClaimants queues the Crypts whose secrets were claimed by crypt, so that they write them once it no longer
+// does.
+func (c *Controller) enqueueClaimants(crypt *v1alpha1.Crypt) {
+	key, err := cache.MetaNamespaceKeyFunc(crypt)
+	if err != nil {
+		return
+	}
+
+	crypts, err := c.cryptLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+
+	for _, other := range crypts {
+		for _, result := range other.Status.Secrets {
+			if result.ClaimedBy == key {
+				c.enqueueCrypt(other)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+// newClaimingCrypts returns two crypts defining the same secret in test-ns1, the first one older than the second.
+func newClaimingCrypts(secretdef v1alpha1.SecretDefinition) (*v1alpha1.Crypt, *v1alpha1.Crypt) {
+	var crypts []*v1alpha1.Crypt
+	for i, namespace := range []string{"team-b", "team-a"} {
+		crypt := newCrypt(&cryptOpts{
+			name:             "test-crypt",
+			namespace:        namespace,
+			targetNamespaces: []string{"test-ns1"},
+			secrets:          []v1alpha1.SecretDefinition{secretdef},
+		})
+		crypt.UID = types.UID(namespace + "-uid")
+		crypt.CreationTimestamp = metav1.NewTime(testTime.Add(time.Duration(i) * time.Hour))
+		crypts = append(crypts, crypt)
+	}
+	return crypts[0], crypts[1]
+}
+
+func TestPrecedes(t *testing.T) {
+	older, newer := newClaimingCrypts(v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"})
+	if !precedes(older, newer) || precedes(newer, older) {
+		t.Error("expected the oldest crypt to take precedence")
+	}
+
+	newer.CreationTimestamp = older.CreationTimestamp
+	if !precedes(newer, older) || precedes(older, newer) {
+		t.Error("expected crypts created at the same time to be ordered by namespace")
+	}
+}
+
+func TestClaimedSecretNotWritten(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"}
+	older, newer := newClaimingCrypts(secretdef)
+
+	f.cryptLister = append(f.cryptLister, older, newer)
+	f.cryptObjects = append(f.cryptObjects, older, newer)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+
+	results := []v1alpha1.SecretStatus{claimedStatus(secretdef, "test-ns1", older)}
+	f.expectUpdateCryptStatusAction(newer, results)
+
+	f.run(getKey(newer, t))
+
+	status := cryptStatus(newer, results, testTime)
+	if condition := status.GetCondition(v1alpha1.CryptConflict); condition == nil || condition.Reason != SecretsClaimed {
+		t.Errorf("expected a %s condition, got %+v", SecretsClaimed, condition)
+	}
+	// the secret is not one the crypt failed to sync
+	if condition := status.GetCondition(v1alpha1.CryptReady); condition == nil || condition.Status != v1.ConditionTrue {
+		t.Errorf("expected the crypt to be ready, got %+v", condition)
+	}
+
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, SuccessSynced) {
+		t.Errorf("unexpected event %q", event)
+	}
+}
+
+func TestClaimedSecretTakenOver(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"}
+	older, newer := newClaimingCrypts(secretdef)
+
+	f.cryptLister = append(f.cryptLister, older, newer)
+	f.cryptObjects = append(f.cryptObjects, older, newer)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+
+	// the newer crypt wrote the secret before the older one could
+	live := newSecret(obj.GetData(), secretdef, newer, "test-ns1")
+	live.ResourceVersion = "1"
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(live)
+	f.kubeObjects = append(f.kubeObjects, live)
+
+	expected := newSecret(obj.GetData(), secretdef, older, "test-ns1")
+	expected.ResourceVersion = live.ResourceVersion
+	f.expectUpdateSecretAction(expected)
+	f.expectUpdateCryptStatusAction(older, []v1alpha1.SecretStatus{secretStatus(secretdef, "test-ns1", nil)})
+
+	f.run(getKey(older, t))
+}
+
+func TestClaimantsEnqueued(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"}
+	older, newer := newClaimingCrypts(secretdef)
+	newer.Status.Secrets = []v1alpha1.SecretStatus{claimedStatus(secretdef, "test-ns1", older)}
+
+	f.cryptLister = append(f.cryptLister, older, newer)
+	f.initControllerLists()
+
+	f.controller.enqueueClaimants(older)
+
+	queued := make(chan interface{})
+	go func() {
+		key, _ := f.controller.queue.Get()
+		queued <- key
+	}()
+
+	select {
+	case key := <-queued:
+		if key != getKey(newer, t) {
+			t.Errorf("expected %s to be queued, got %v", getKey(newer, t), key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the claimant to be queued")
+	}
+}
+
+func TestPrecedingClaims(t *testing.T) {
+	f := newFixture(t)
+
+	older, newer := newClaimingCrypts(v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"})
+	older.Spec.Secrets = append(older.Spec.Secrets, v1alpha1.SecretDefinition{Prefix: "test/"})
+	older.Generation = 1
+
+	// a secret the older crypt created for a key under its prefix
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-bar-secret",
+			Namespace:   "test-ns1",
+			Labels:      map[string]string{CryptUIDLabel: string(older.UID), ManagedByLabel: ComponentName},
+			Annotations: map[string]string{PrefixAnnotation: "test/"},
+		},
+	})
+
+	f.cryptLister = append(f.cryptLister, older, newer)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+	f.initControllerLists()
+
+	claimed, err := f.controller.precedingClaims(newer, []string{"test-ns1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"test-ns1/test-foo-secret", "test-ns1/test-bar-secret"} {
+		if claimed[name] != older {
+			t.Errorf("expected %s to be claimed by the older crypt, got %v", name, claimed[name])
+		}
+	}
+
+	// the claims of a crypt are worked out again only once its spec changes
+	cl := f.controller.claimants[getKey(older, t)]
+	if f.controller.claimantFor(getKey(older, t), older) != cl {
+		t.Error("expected the claims of an unchanged crypt to be kept")
+	}
+	older.Generation++
+	if f.controller.claimantFor(getKey(older, t), older) == cl {
+		t.Error("expected the claims of a changed crypt to be worked out again")
+	}
+
+	f.controller.handleCryptDelete(older)
+	if _, ok := f.controller.claimants[getKey(older, t)]; ok {
+		t.Error("expected the claims of a deleted crypt to be forgotten")
+	}
+}
@@ -1,21 +1,23 @@
 package controller
 
 import (
+	"context"
 	"fmt"
-	"regexp"
+	"sync"
 	"time"
 
 	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/validation"
 	clientset "github.com/bluehoodie/crypt-controller/pkg/client/clientset/versioned"
 	cryptscheme "github.com/bluehoodie/crypt-controller/pkg/client/clientset/versioned/scheme"
 	informers "github.com/bluehoodie/crypt-controller/pkg/client/informers/externalversions/crypt/v1alpha1"
 	listers "github.com/bluehoodie/crypt-controller/pkg/client/listers/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
 	"github.com/bluehoodie/crypt-controller/pkg/store"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -37,10 +39,23 @@ const (
 
 	// MessageResourceSynced is the message used for an Event fired when a Crypt is synced successfully
 	MessageResourceSynced = "Crypt synced successfully"
+
+	// DefaultStoreTimeout bounds each read from a store
+	DefaultStoreTimeout = 30 * time.Second
+
+	// CryptUIDLabel is set on the secrets created by the controller to the UID of the Crypt they were created for
+	CryptUIDLabel = "core.bluehoodie.io/crypt-uid"
+
+	// CryptAnnotation is set on the secrets created by the controller to the namespace/name of their Crypt
+	CryptAnnotation = "core.bluehoodie.io/crypt"
+
+	// ManagedByLabel is set on the secrets created by the controller to ComponentName
+	ManagedByLabel = "app.kubernetes.io/managed-by"
 )
 
 type Controller struct {
-	queue workqueue.RateLimitingInterface
+	queue      workqueue.RateLimitingInterface
+	storeQueue workqueue.RateLimitingInterface
 
 	kubeClientset  kubernetes.Interface
 	cryptClientset clientset.Interface
@@ -52,9 +67,36 @@ type Controller struct {
 	cryptInformerSynced     cache.InformerSynced
 	cryptLister             listers.CryptLister
 
+	secretStoreInformerSynced        cache.InformerSynced
+	secretStoreLister                listers.SecretStoreLister
+	clusterSecretStoreInformerSynced cache.InformerSynced
+	clusterSecretStoreLister         listers.ClusterSecretStoreLister
+
 	recorder record.EventRecorder
 
-	store store.Store
+	stores       *store.Registry
+	storeTimeout time.Duration
+
+	watchesMu sync.Mutex
+	watches   map[storeKey]context.CancelFunc
+	// the keys each Crypt read during its last sync, including those found under its prefixes
+	cryptKeys map[string]map[storeKey]struct{}
+
+	readsMu sync.Mutex
+	reads   map[storeKey]*storeRead
+
+	// the secrets of the controller by the UID of their Crypt
+	secretIndexer cache.Indexer
+
+	claimantsMu sync.Mutex
+	claimants   map[string]*claimant
+
+	healthMu         sync.Mutex
+	syncing          map[string]time.Time
+	pingErrors       map[string]error
+	stuckSyncTimeout time.Duration
+
+	now func() metav1.Time
 }
 
 type Option func(*Controller)
@@ -65,13 +107,29 @@ func WithEventRecorder(recorder record.EventRecorder) Option {
 	}
 }
 
+// WithStoreTimeout sets how long a single read from a store may take.
+func WithStoreTimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.storeTimeout = timeout
+	}
+}
+
+// WithStuckSyncTimeout sets how long a worker may sync a single Crypt before WorkersAlive reports it.
+func WithStuckSyncTimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.stuckSyncTimeout = timeout
+	}
+}
+
 func New(
 	kubeClientset kubernetes.Interface,
 	cryptClientset clientset.Interface,
 	namespaceInformer coreinformers.NamespaceInformer,
 	secreteInformer coreinformers.SecretInformer,
 	cryptInformer informers.CryptInformer,
-	store store.Store,
+	secretStoreInformer informers.SecretStoreInformer,
+	clusterSecretStoreInformer informers.ClusterSecretStoreInformer,
+	stores *store.Registry,
 	opts ...Option,
 ) *Controller {
 	utilruntime.Must(cryptscheme.AddToScheme(scheme.Scheme))
@@ -87,9 +145,25 @@ func New(
 		cryptInformerSynced:     cryptInformer.Informer().HasSynced,
 		cryptLister:             cryptInformer.Lister(),
 
-		store: store,
-
-		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ComponentName),
+		secretStoreInformerSynced:        secretStoreInformer.Informer().HasSynced,
+		secretStoreLister:                secretStoreInformer.Lister(),
+		clusterSecretStoreInformerSynced: clusterSecretStoreInformer.Informer().HasSynced,
+		clusterSecretStoreLister:         clusterSecretStoreInformer.Lister(),
+
+		stores:       stores,
+		storeTimeout: DefaultStoreTimeout,
+		watches:      make(map[storeKey]context.CancelFunc),
+		cryptKeys:    make(map[string]map[storeKey]struct{}),
+		reads:        make(map[storeKey]*storeRead),
+		claimants:    make(map[string]*claimant),
+		now:          metav1.Now,
+
+		syncing:          make(map[string]time.Time),
+		pingErrors:       make(map[string]error),
+		stuckSyncTimeout: DefaultStuckSyncTimeout,
+
+		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ComponentName),
+		storeQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ComponentName+"-stores"),
 	}
 
 	for _, opt := range opts {
@@ -100,25 +174,51 @@ func New(
 		setDefaultRecorder(c)
 	}
 
+	if err := secreteInformer.Informer().AddIndexers(cache.Indexers{cryptUIDIndex: indexByCryptUID}); err != nil {
+		utilruntime.HandleError(fmt.Errorf("could not index secrets: %v", err))
+	}
+	c.secretIndexer = secreteInformer.Informer().GetIndexer()
+
 	cryptInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			c.enqueueCrypt(obj)
 		},
 		UpdateFunc: func(old, new interface{}) {
-			c.enqueueCrypt(new)
+			c.handleCryptUpdate(old, new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.handleCryptDelete(obj)
 		},
 	})
 
 	secreteInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			c.handleSecretUpdate(old, new)
+		},
 		DeleteFunc: func(obj interface{}) {
 			c.handleSecretDelete(obj)
 		},
 	})
 
+	secretStoreHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueueSecretStore(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			c.handleSecretStoreUpdate(old, new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueueSecretStore(obj)
+		},
+	}
+	secretStoreInformer.Informer().AddEventHandler(secretStoreHandler)
+	clusterSecretStoreInformer.Informer().AddEventHandler(secretStoreHandler)
+
 	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			c.handleNamespaceAdd(obj)
 		},
+		UpdateFunc: c.handleNamespaceUpdate,
 	})
 
 	return c
@@ -127,9 +227,22 @@ func New(
 func (c *Controller) Run(workers int, stopChan <-chan struct{}) error {
 	defer utilruntime.HandleCrash() //soon to be deprecated?
 	defer c.queue.ShutDown()
+	defer c.storeQueue.ShutDown()
+	defer c.stopWatches()
 
 	log.Info("starting Crypt controller")
 
+	// reads from the stores are cancelled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	timeoutChan := make(chan struct{})
 	go func() {
 		defer close(timeoutChan)
@@ -141,14 +254,17 @@ func (c *Controller) Run(workers int, stopChan <-chan struct{}) error {
 		}
 	}()
 
-	ok := cache.WaitForCacheSync(timeoutChan, c.namespaceInformerSynced, c.secretInformerSynced, c.cryptInformerSynced)
+	ok := cache.WaitForCacheSync(timeoutChan, c.namespaceInformerSynced, c.secretInformerSynced, c.cryptInformerSynced,
+		c.secretStoreInformerSynced, c.clusterSecretStoreInformerSynced)
 	if !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
 	log.Info("starting workers")
+	go wait.Until(c.runStoreWorker, time.Second, stopChan)
+	go wait.Until(c.pruneWatches, time.Minute, stopChan)
 	for i := 0; i < workers; i++ {
-		go wait.Until(c.runWorker, time.Second, stopChan)
+		go wait.Until(func() { c.runWorker(ctx) }, time.Second, stopChan)
 	}
 
 	log.Info("started workers")
@@ -158,12 +274,12 @@ func (c *Controller) Run(workers int, stopChan <-chan struct{}) error {
 	return nil
 }
 
-func (c *Controller) runWorker() {
-	for c.processNextWorkItem() {
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
 	}
 }
 
-func (c *Controller) processNextWorkItem() bool {
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	obj, shutdown := c.queue.Get()
 	if shutdown {
 		return false
@@ -181,7 +297,17 @@ func (c *Controller) processNextWorkItem() bool {
 			return nil
 		}
 
-		if err := c.syncHandler(key); err != nil {
+		start := time.Now()
+		c.startSync(key)
+		err := c.syncHandler(ctx, key)
+		c.endSync(key)
+		metrics.ObserveReconcile(key, time.Since(start), err)
+		if isHeld(err) {
+			c.queue.Forget(obj)
+			log.Infof("synced %s: %v", key, err)
+			return nil
+		}
+		if err != nil {
 			c.queue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
@@ -212,7 +338,7 @@ func (c *Controller) enqueueCrypt(obj interface{}) {
 	c.queue.AddRateLimited(key)
 }
 
-func (c *Controller) syncHandler(key string) error {
+func (c *Controller) syncHandler(ctx context.Context, key string) error {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
@@ -225,28 +351,157 @@ func (c *Controller) syncHandler(key string) error {
 			utilruntime.HandleError(fmt.Errorf("crypt %s in work queue no longer exists", key))
 			return nil
 		}
+		return err
+	}
+
+	if crypt.DeletionTimestamp != nil {
+		if hasFinalizer(crypt) {
+			return c.finalizeCrypt(crypt)
+		}
+		return nil
 	}
 
-	var namespaceMatches []string
-	for _, pattern := range crypt.Spec.Namespaces {
-		namespaceMatches = append(namespaceMatches, c.findNamespaceMatches(pattern)...)
+	if !hasFinalizer(crypt) {
+		if crypt, err = c.addFinalizer(crypt); err != nil {
+			return err
+		}
+	}
+
+	// the spec has to change before the crypt can be synced, and nothing is pruned meanwhile
+	if errs := validation.ValidateCrypt(crypt); len(errs) > 0 {
+		log.Infof("invalid crypt %s: %v", key, errs.ToAggregate())
+		return c.updateStatusInvalid(crypt, InvalidSpec, errs.ToAggregate())
+	}
+
+	matcher, err := newNamespaceMatcher(crypt)
+	if err != nil {
+		return c.updateStatusInvalid(crypt, InvalidSpec, err)
+	}
+
+	namespaceMatches, err := c.targetNamespaces(matcher)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := c.precedingClaims(crypt, namespaceMatches)
+	if err != nil {
+		return err
 	}
 
 	// create secrets in the appropriate namespaces
+	var results []v1alpha1.SecretStatus
+	var failures []error
+	held := 0
+	reads := make(keyCache)
+	desired := make(map[string]struct{})
+	keys := make(map[storeKey]struct{})
+	unlistedPrefixes := make(map[string]struct{})
+	// the names defined explicitly win over those of keys under a prefix, and those of earlier prefixes over later ones
+	names := make(map[string]string)
 	for _, sec := range crypt.Spec.Secrets {
-		for _, ns := range namespaceMatches {
-			if _, err := c.createSecret(sec, crypt, ns); err != nil {
-				log.Infof("could not create secret for key %s in namespace %s: %v", key, namespace, err)
+		if sec.GetPrefix() == "" {
+			names[sec.GetName()] = sec.GetKey()
+		}
+	}
+	for _, sec := range crypt.Spec.Secrets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		defs := []v1alpha1.SecretDefinition{sec}
+		if sec.GetPrefix() != "" {
+			if defs, err = c.expandPrefix(ctx, sec, crypt); err != nil {
+				log.Warningf("could not list keys under prefix %s for %s: %v", sec.GetPrefix(), key, err)
+				c.recordPrefixFailure(crypt, sec, err)
+				results = append(results, prefixStatus(sec, err))
+				if isTransient(err) {
+					failures = append(failures, fmt.Errorf("prefix %s: %v", sec.GetPrefix(), err))
+				} else {
+					held++
+				}
+				unlistedPrefixes[sec.GetPrefix()] = struct{}{}
+				continue
+			}
+		}
+
+		for _, def := range defs {
+			// a key under a prefix mapping to the name of another secret of the crypt would overwrite it on every sync
+			var collision error
+			if sec.GetPrefix() != "" {
+				if other, ok := names[def.GetName()]; ok {
+					collision = &nameCollisionError{name: def.GetName(), key: def.GetKey(), other: other}
+				} else {
+					names[def.GetName()] = def.GetKey()
+				}
+			}
+
+			// changes to the key are picked up without waiting for the next resync when the store can watch it
+			if st, err := c.storeFor(def, crypt); err == nil && collision == nil {
+				keys[storeKey{store: st, key: def.GetKey()}] = struct{}{}
+				c.watchKey(ctx, st, def.GetKey())
+			}
+
+			// failures are recorded once per reason for all the namespaces they happened in
+			var failed failedNamespaces
+			for _, ns := range namespaceMatches {
+				desired[ns+"/"+def.GetName()] = struct{}{}
+
+				// the secret is left to the winner, which takes it over, rather than pruned
+				if winner, ok := claimed[ns+"/"+def.GetName()]; ok && collision == nil {
+					log.V(4).Infof("secret %s/%s of %s is defined by crypt %s/%s", ns, def.GetName(), key, winner.Namespace, winner.Name)
+					results = append(results, claimedStatus(def, ns, winner))
+					continue
+				}
+
+				err := collision
+				if err == nil {
+					_, err = c.createSecret(ctx, reads, def, crypt, ns)
+				}
+				if err != nil {
+					log.Warningf("could not sync secret %s/%s of %s: %v", ns, def.GetName(), key, err)
+					failed.add(ns, err)
+					if isTransient(err) {
+						failures = append(failures, fmt.Errorf("secret %s/%s: %v", ns, def.GetName(), err))
+					} else {
+						held++
+					}
+				}
+				results = append(results, secretStatus(def, ns, err))
 			}
+			c.recordFailures(crypt, def, failed)
 		}
 	}
 
+	c.recordKeys(key, keys, len(unlistedPrefixes) > 0)
+
+	if crypt.Spec.GetPrunePolicy() == v1alpha1.PrunePolicyDelete && crypt.Spec.GetCreationPolicy() != v1alpha1.CreationPolicyOrphan {
+		c.pruneSecrets(crypt, desired, unlistedPrefixes)
+	}
+
+	if err := c.updateStatus(crypt, results); err != nil {
+		return err
+	}
+
+	// the crypt is synced again with a backoff until every secret is, except for the failures retrying will not fix,
+	// which are left in the status until the next resync
+	if len(failures) > 0 {
+		return utilerrors.NewAggregate(failures)
+	}
+	if held > 0 {
+		return &heldError{count: held}
+	}
+
 	c.recorder.Event(crypt, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
 	return nil
 }
 
-func (c *Controller) createSecret(sec v1alpha1.SecretDefinition, crypt *v1alpha1.Crypt, namespace string) (*corev1.Secret, error) {
-	obj, err := c.store.Get(sec.GetKey())
+func (c *Controller) createSecret(ctx context.Context, reads keyCache, sec v1alpha1.SecretDefinition, crypt *v1alpha1.Crypt, namespace string) (*corev1.Secret, error) {
+	st, err := c.storeFor(sec, crypt)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := c.readKey(ctx, reads, st, sec.GetKey())
 	if err != nil {
 		log.Errorf("could not get value from store: %v", err)
 		return nil, err
@@ -254,12 +509,35 @@ func (c *Controller) createSecret(sec v1alpha1.SecretDefinition, crypt *v1alpha1
 
 	secret := newSecret(obj.GetData(), sec, crypt, namespace)
 
-	var result *corev1.Secret
-	result, err = c.kubeClientset.CoreV1().Secrets(namespace).Create(secret)
-	if err != nil && errors.IsAlreadyExists(err) {
-		result, err = c.kubeClientset.CoreV1().Secrets(namespace).Update(secret)
+	live, err := c.secretLister.Secrets(namespace).Get(secret.Name)
+	if err == nil {
+		return c.updateSecret(crypt, sec, live, secret)
+	}
+
+	result, err := c.kubeClientset.CoreV1().Secrets(namespace).Create(secret)
+	if errors.IsAlreadyExists(err) {
+		// the lister has not seen the secret yet
+		if live, err = c.kubeClientset.CoreV1().Secrets(namespace).Get(secret.Name, metav1.GetOptions{}); err != nil {
+			return nil, &writeError{err: err}
+		}
+		return c.updateSecret(crypt, sec, live, secret)
+	}
+	if err != nil {
+		return nil, &writeError{err: err}
+	}
+
+	metrics.SecretOperation(metrics.SecretCreated)
+	return result, nil
+}
+
+// handleCryptDelete drops the metrics of a deleted Crypt.
+func (c *Controller) handleCryptDelete(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
 	}
-	return result, err
+	metrics.ForgetCrypt(key)
+	c.forgetClaimant(key)
 }
 
 func (c *Controller) handleNamespaceAdd(obj interface{}) {
@@ -268,28 +546,21 @@ func (c *Controller) handleNamespaceAdd(obj interface{}) {
 		return
 	}
 
-	nsList, err := c.namespaceLister.List(labels.NewSelector())
-	if err != nil {
+	c.enqueueCryptsForNamespace(namespace)
+}
+
+func (c *Controller) handleSecretUpdate(old, new interface{}) {
+	oldSecret, ok := old.(*corev1.Secret)
+	if !ok {
 		return
 	}
-
-	for _, ns := range nsList {
-		cryptList, err := c.cryptLister.Crypts(ns.Name).List(labels.NewSelector())
-		if err != nil {
-			continue
-		}
-
-	Out:
-		for _, crypt := range cryptList {
-			for _, namespacePattern := range crypt.Spec.Namespaces {
-				match, _ := regexp.MatchString(namespacePattern, namespace.Name)
-				if match {
-					c.enqueueCrypt(crypt)
-					break Out
-				}
-			}
-		}
+	newSecret, ok := new.(*corev1.Secret)
+	if !ok || oldSecret.ResourceVersion == newSecret.ResourceVersion {
+		return
 	}
+
+	c.enqueueSecretStoresForSecret(newSecret)
+	c.handleSecretDrift(newSecret)
 }
 
 func (c *Controller) handleSecretDelete(obj interface{}) {
@@ -308,54 +579,32 @@ func (c *Controller) handleSecretDelete(obj interface{}) {
 		}
 	}
 
-	if ownerRef := metav1.GetControllerOf(secret); ownerRef != nil {
-		// If this object is not owned by a Crypt, we should not do anything more with it.
-		if ownerRef.Kind != "Crypt" {
-			return
-		}
-
-		//find the right crypt associated with this secret across all namespaces
-		var crypt *v1alpha1.Crypt
-
-		crypts, _ := c.cryptClientset.CoreV1alpha1().Crypts("").List(metav1.ListOptions{})
-		found := false
-		namespacesChecked := make(map[string]struct{})
-		for _, ci := range crypts.Items {
-			namespace := ci.Namespace
-			if _, ok := namespacesChecked[namespace]; ok {
-				continue
-			}
-			namespacesChecked[namespace] = struct{}{}
-
-			crypt, _ = c.cryptLister.Crypts(namespace).Get(ownerRef.Name)
-			if crypt != nil {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			log.V(4).Infof("ignoring orphaned object '%s' of foo '%s'", secret.GetSelfLink(), ownerRef.Name)
-			return
-		}
-
+	if crypt := c.cryptForSecret(secret); crypt != nil {
 		c.enqueueCrypt(crypt)
-		return
 	}
 }
 
-func (c *Controller) findNamespaceMatches(namespacePattern string) []string {
-	var result []string
+// cryptForSecret returns the Crypt a secret was created for, or nil when it was not created by the controller or its
+// Crypt is gone.
+func (c *Controller) cryptForSecret(secret *corev1.Secret) *v1alpha1.Crypt {
+	// If this object was not created for a Crypt, we should not do anything more with it.
+	key, ok := secret.Annotations[CryptAnnotation]
+	if !ok {
+		return nil
+	}
 
-	namespaces, _ := c.namespaceLister.List(labels.NewSelector())
-	for _, ns := range namespaces {
-		match, _ := regexp.MatchString(namespacePattern, ns.Name)
-		if match {
-			result = append(result, ns.Name)
-		}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
 	}
 
-	return result
+	crypt, err := c.cryptLister.Crypts(namespace).Get(name)
+	if err != nil || string(crypt.UID) != secret.Labels[CryptUIDLabel] {
+		log.V(4).Infof("ignoring orphaned secret %s/%s of crypt %s", secret.Namespace, secret.Name, key)
+		return nil
+	}
+
+	return crypt
 }
 
 func newSecret(data map[string][]byte, secdef v1alpha1.SecretDefinition, parentCrypt *v1alpha1.Crypt, targetNamepsace string) *corev1.Secret {
@@ -365,25 +614,38 @@ func newSecret(data map[string][]byte, secdef v1alpha1.SecretDefinition, parentC
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secdef.GetName(),
-			Namespace: targetNamepsace,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(parentCrypt, schema.GroupVersionKind{
-					Group:   v1alpha1.SchemeGroupVersion.Group,
-					Version: v1alpha1.SchemeGroupVersion.Version,
-					Kind:    "Crypt",
-				}),
-			},
-			Labels:      secdef.GetLabels(),
-			Annotations: secdef.GetAnnotations(),
+			Name:        secdef.GetName(),
+			Namespace:   targetNamepsace,
+			Labels:      secretLabels(secdef, parentCrypt),
+			Annotations: secretAnnotations(secdef, parentCrypt),
 		},
 		Type: corev1.SecretType(secdef.GetType()),
 		Data: data,
 	}
+	secret.Annotations[ContentHashAnnotation] = contentHash(secret)
 
 	return secret
 }
 
+func secretLabels(secdef v1alpha1.SecretDefinition, parentCrypt *v1alpha1.Crypt) map[string]string {
+	result := make(map[string]string, len(secdef.GetLabels())+2)
+	for k, v := range secdef.GetLabels() {
+		result[k] = v
+	}
+	result[CryptUIDLabel] = string(parentCrypt.UID)
+	result[ManagedByLabel] = ComponentName
+	return result
+}
+
+func secretAnnotations(secdef v1alpha1.SecretDefinition, parentCrypt *v1alpha1.Crypt) map[string]string {
+	result := make(map[string]string, len(secdef.GetAnnotations())+1)
+	for k, v := range secdef.GetAnnotations() {
+		result[k] = v
+	}
+	result[CryptAnnotation] = parentCrypt.Namespace + "/" + parentCrypt.Name
+	return result
+}
+
 func setDefaultRecorder(c *Controller) {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(log.Infof)
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"context"
 	"k8s.io/client-go/tools/record"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +28,13 @@ import (
 var (
 	alwaysReady        = func() bool { return true }
 	noResyncPeriodFunc = func() time.Duration { return 0 }
+
+	testTime = metav1.NewTime(time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC))
+)
+
+const (
+	defaultTestStore = "default"
+	otherTestStore   = "other"
 )
 
 type cryptOpts struct {
@@ -40,8 +49,9 @@ func newCrypt(opts *cryptOpts) *v1alpha1.Crypt {
 	return &v1alpha1.Crypt{
 		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String()},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      opts.name,
-			Namespace: opts.namespace,
+			Name:       opts.name,
+			Namespace:  opts.namespace,
+			Finalizers: []string{CryptFinalizer},
 		},
 		Spec: v1alpha1.CryptSpec{
 			Secrets:    opts.secrets,
@@ -77,7 +87,8 @@ type fixture struct {
 	kubeActions  []core.Action
 	cryptActions []core.Action
 
-	store store.Store
+	store  store.Store
+	stores *store.Registry
 }
 
 func newFixture(t *testing.T) *fixture {
@@ -88,8 +99,16 @@ func newFixture(t *testing.T) *fixture {
 	testStoreMap := make(map[string]store.Object)
 	testStoreMap["test/foo"] = store.Object(map[string][]byte{"foo": []byte("fooSecret")})
 	testStoreMap["test/bar"] = store.Object(map[string][]byte{"bar": []byte("barSecret")})
-	store, _ := memory.New(testStoreMap)
-	f.store = store
+	testStore, _ := memory.New(testStoreMap)
+	f.store = testStore
+
+	otherStoreMap := make(map[string]store.Object)
+	otherStoreMap["test/foo"] = store.Object(map[string][]byte{"foo": []byte("otherFooSecret")})
+	otherStore, _ := memory.New(otherStoreMap)
+
+	f.stores = store.NewRegistry(defaultTestStore)
+	f.stores.Register(defaultTestStore, f.store)
+	f.stores.Register(otherTestStore, otherStore)
 
 	f.kubeObjects = []runtime.Object{}
 	f.cryptObjects = []runtime.Object{}
@@ -113,15 +132,38 @@ func (f *fixture) initController() {
 		f.k8sInformer.Core().V1().Namespaces(),
 		f.k8sInformer.Core().V1().Secrets(),
 		f.cryptInformer.Core().V1alpha1().Crypts(),
-		f.store,
+		f.cryptInformer.Core().V1alpha1().SecretStores(),
+		f.cryptInformer.Core().V1alpha1().ClusterSecretStores(),
+		f.stores,
 		WithEventRecorder(record.NewFakeRecorder(10)),
 	)
 	f.controller.cryptInformerSynced = alwaysReady
 	f.controller.namespaceInformerSynced = alwaysReady
 	f.controller.secretInformerSynced = alwaysReady
+	f.controller.secretStoreInformerSynced = alwaysReady
+	f.controller.clusterSecretStoreInformerSynced = alwaysReady
+	f.controller.now = func() metav1.Time { return testTime }
 }
 
 func (f *fixture) initControllerLists() {
+	// the clientsets are built before the test adds its objects
+	for _, o := range f.cryptObjects {
+		if crypt, ok := o.(*v1alpha1.Crypt); ok {
+			f.cryptclient.CoreV1alpha1().Crypts(crypt.Namespace).Create(crypt)
+		}
+	}
+	f.cryptclient.ClearActions()
+
+	for _, o := range f.kubeObjects {
+		switch obj := o.(type) {
+		case *v1.Namespace:
+			f.kubeclient.CoreV1().Namespaces().Create(obj)
+		case *v1.Secret:
+			f.kubeclient.CoreV1().Secrets(obj.Namespace).Create(obj)
+		}
+	}
+	f.kubeclient.ClearActions()
+
 	for _, o := range f.cryptLister {
 		f.cryptInformer.Core().V1alpha1().Crypts().Informer().GetIndexer().Add(o)
 	}
@@ -148,7 +190,11 @@ func (f *fixture) runController(cryptName string, expectError bool) {
 	f.k8sInformer.Start(stop)
 	f.cryptInformer.Start(stop)
 
-	err := f.controller.syncHandler(cryptName)
+	err := f.controller.syncHandler(context.Background(), cryptName)
+	// failures that are not retried are checked through the status, and only errors requeuing the crypt are expected
+	if isHeld(err) {
+		err = nil
+	}
 	if !expectError && err != nil {
 		f.t.Errorf("error syncing crypt: %v", err)
 	} else if expectError && err == nil {
@@ -182,6 +228,30 @@ func (f *fixture) expectCreateSecretAction(secret *v1.Secret) {
 	f.kubeActions = append(f.kubeActions, core.NewCreateAction(schema.GroupVersionResource{Resource: "secrets"}, secret.Namespace, secret))
 }
 
+func (f *fixture) expectUpdateSecretAction(secret *v1.Secret) {
+	f.kubeActions = append(f.kubeActions, core.NewUpdateAction(schema.GroupVersionResource{Resource: "secrets"}, secret.Namespace, secret))
+}
+
+func (f *fixture) expectDeleteSecretAction(secret *v1.Secret) {
+	f.kubeActions = append(f.kubeActions, core.NewDeleteAction(schema.GroupVersionResource{Resource: "secrets"}, secret.Namespace, secret.Name))
+}
+
+func (f *fixture) expectUpdateCryptStatusAction(crypt *v1alpha1.Crypt, results []v1alpha1.SecretStatus) {
+	cryptCopy := crypt.DeepCopy()
+	cryptCopy.Status = cryptStatus(crypt, results, testTime)
+	action := core.NewUpdateAction(schema.GroupVersionResource{Resource: "crypts"}, crypt.Namespace, cryptCopy)
+	action.Subresource = "status"
+	f.cryptActions = append(f.cryptActions, action)
+}
+
+func (f *fixture) expectUpdateCryptInvalidStatusAction(crypt *v1alpha1.Crypt, reason string, err error) {
+	cryptCopy := crypt.DeepCopy()
+	cryptCopy.Status = invalidStatus(crypt, reason, err, testTime)
+	action := core.NewUpdateAction(schema.GroupVersionResource{Resource: "crypts"}, crypt.Namespace, cryptCopy)
+	action.Subresource = "status"
+	f.cryptActions = append(f.cryptActions, action)
+}
+
 func filterInformerActions(actions []core.Action) []core.Action {
 	ret := make([]core.Action, 0, 0)
 	for _, action := range actions {
@@ -191,8 +261,11 @@ func filterInformerActions(actions []core.Action) []core.Action {
 			action.Matches("watch", "namespaces") ||
 			action.Matches("update", "namespaces") ||
 			action.Matches("list", "secrets") ||
-			action.Matches("update", "secrets") ||
-			action.Matches("watch", "secrets") {
+			action.Matches("watch", "secrets") ||
+			action.Matches("list", "secretstores") ||
+			action.Matches("watch", "secretstores") ||
+			action.Matches("list", "clustersecretstores") ||
+			action.Matches("watch", "clustersecretstores") {
 			continue
 		}
 		ret = append(ret, action)
@@ -288,13 +361,16 @@ func TestSecretsCreated(t *testing.T) {
 		f.kubeObjects = append(f.kubeObjects, ns)
 	}
 
+	var results []v1alpha1.SecretStatus
 	for _, secretdef := range secretDefinitions {
-		obj, _ := f.store.Get(secretdef.Key)
+		obj, _ := f.store.Get(context.Background(), secretdef.Key)
 		for _, namespace := range namespaceStrings {
 			expectedSecret := newSecret(obj.GetData(), secretdef, crypt, namespace)
 			f.expectCreateSecretAction(expectedSecret)
+			results = append(results, secretStatus(secretdef, namespace, nil))
 		}
 	}
+	f.expectUpdateCryptStatusAction(crypt, results)
 
 	f.run(getKey(crypt, t))
 }
@@ -336,13 +412,174 @@ func TestSecretsCreatedNamespacePatterns(t *testing.T) {
 		f.kubeObjects = append(f.kubeObjects, ns)
 	}
 
+	var results []v1alpha1.SecretStatus
 	for _, secretdef := range secretDefinitions {
-		obj, _ := f.store.Get(secretdef.Key)
+		obj, _ := f.store.Get(context.Background(), secretdef.Key)
 		for _, namespace := range namespaceStrings {
 			expectedSecret := newSecret(obj.GetData(), secretdef, crypt, namespace)
 			f.expectCreateSecretAction(expectedSecret)
+			results = append(results, secretStatus(secretdef, namespace, nil))
 		}
 	}
+	f.expectUpdateCryptStatusAction(crypt, results)
+
+	f.run(getKey(crypt, t))
+}
+
+func TestSecretsCreatedFromNamedStore(t *testing.T) {
+	f := newFixture(t)
+
+	secretDefinitions := []v1alpha1.SecretDefinition{
+		{
+			Name: "test-foo-secret",
+			Key:  "test/foo",
+		},
+		{
+			Name:  "test-other-foo-secret",
+			Key:   "test/foo",
+			Store: otherTestStore,
+		},
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          secretDefinitions,
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	var results []v1alpha1.SecretStatus
+	for _, secretdef := range secretDefinitions {
+		st, _ := f.stores.Get(secretdef.Store)
+		obj, _ := st.Get(context.Background(), secretdef.Key)
+		f.expectCreateSecretAction(newSecret(obj.GetData(), secretdef, crypt, namespace.Name))
+		results = append(results, secretStatus(secretdef, namespace.Name, nil))
+	}
+	f.expectUpdateCryptStatusAction(crypt, results)
 
 	f.run(getKey(crypt, t))
 }
+
+func TestCryptStatusReportsFailures(t *testing.T) {
+	f := newFixture(t)
+
+	secretDefinitions := []v1alpha1.SecretDefinition{
+		{
+			Name: "test-foo-secret",
+			Key:  "test/foo",
+		},
+		{
+			Name: "test-missing-secret",
+			Key:  "test/missing",
+		},
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          secretDefinitions,
+	})
+	crypt.Generation = 3
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	obj, _ := f.store.Get(context.Background(), "test/foo")
+	f.expectCreateSecretAction(newSecret(obj.GetData(), secretDefinitions[0], crypt, namespace.Name))
+
+	expected := crypt.DeepCopy()
+	expected.Status = v1alpha1.CryptStatus{
+		ObservedGeneration: 3,
+		LastSyncTime:       &testTime,
+		Conditions: []v1alpha1.CryptCondition{
+			{
+				Type:               v1alpha1.CryptReady,
+				Status:             v1.ConditionFalse,
+				LastTransitionTime: testTime,
+				Reason:             PermanentSyncFailure,
+				Message:            "1 of 2 secrets could not be synced; StoreKeyNotFound failures are not retried",
+			},
+			{
+				Type:               v1alpha1.CryptConflict,
+				Status:             v1.ConditionFalse,
+				LastTransitionTime: testTime,
+				Reason:             NoConflict,
+			},
+		},
+		Secrets: []v1alpha1.SecretStatus{
+			{Namespace: namespace.Name, Name: "test-foo-secret", Key: "test/foo", Synced: true},
+			{Namespace: namespace.Name, Name: "test-missing-secret", Key: "test/missing", Error: store.NotFoundError.Error(), Reason: StoreKeyNotFound},
+		},
+		SecretCount:    2,
+		NamespaceCount: 1,
+	}
+	action := core.NewUpdateAction(schema.GroupVersionResource{Resource: "crypts"}, crypt.Namespace, expected)
+	action.Subresource = "status"
+	f.cryptActions = append(f.cryptActions, action)
+
+	// a missing key is not retried with a backoff
+	f.run(getKey(crypt, t))
+
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, StoreKeyNotFound) || !strings.Contains(event, "secret test-missing-secret") || !strings.Contains(event, "namespaces test-ns1") {
+		t.Errorf("unexpected event %q", event)
+	}
+	if len(events) > 0 {
+		t.Errorf("unexpected event %q", <-events)
+	}
+}
+
+func TestUnchangedStatusNotWritten(t *testing.T) {
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-missing-secret",
+		Key:  "test/missing",
+	}
+
+	tests := map[string]struct {
+		lastSync time.Duration
+		written  bool
+	}{
+		"recently synced": {lastSync: time.Minute},
+		"refreshed":       {lastSync: StatusRefreshInterval, written: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+
+			namespace := newNamespace("test-ns1")
+
+			crypt := newCrypt(&cryptOpts{
+				name:             "test-crypt",
+				namespace:        "default",
+				targetNamespaces: []string{namespace.Name},
+				secrets:          []v1alpha1.SecretDefinition{secretdef},
+			})
+			results := []v1alpha1.SecretStatus{secretStatus(secretdef, namespace.Name, store.NotFoundError)}
+			crypt.Status = cryptStatus(crypt, results, metav1.NewTime(testTime.Add(-test.lastSync)))
+
+			f.cryptLister = append(f.cryptLister, crypt)
+			f.cryptObjects = append(f.cryptObjects, crypt)
+			f.namespaceLister = append(f.namespaceLister, namespace)
+			f.kubeObjects = append(f.kubeObjects, namespace)
+
+			// the missing key is not written, and the status only differs by its lastSyncTime
+			if test.written {
+				f.expectUpdateCryptStatusAction(crypt, results)
+			}
+
+			f.run(getKey(crypt, t))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	log "k8s.io/klog"
+)
+
+const (
+	// ContentHashAnnotation is set on the secrets created by the controller to a digest of their type, data, labels
+	// and annotations, so that changes made by anyone else can be told apart from the controller's own.
+	ContentHashAnnotation = "core.bluehoodie.io/content-hash"
+
+	// SecretDrifted is used as part of the Event 'reason' when a secret created for a Crypt was modified by someone
+	// else
+	SecretDrifted = "SecretDrifted"
+)
+
+// contentHash digests what the controller writes to a secret. empty maps are left out, as the api server drops them.
+func contentHash(secret *corev1.Secret) string {
+	annotations := make(map[string]string, len(secret.Annotations))
+	for k, v := range secret.Annotations {
+		if k != ContentHashAnnotation {
+			annotations[k] = v
+		}
+	}
+
+	content := struct {
+		Type        corev1.SecretType `json:"type"`
+		Data        map[string][]byte `json:"data,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}{
+		Type:        secret.Type,
+		Data:        secret.Data,
+		Labels:      secret.Labels,
+		Annotations: annotations,
+	}
+
+	// maps are marshalled with sorted keys, which makes the digest stable
+	b, _ := json.Marshal(content)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// handleSecretDrift syncs the Crypt of a secret right away when the secret no longer holds what the controller wrote
+// to it.
+func (c *Controller) handleSecretDrift(secret *corev1.Secret) {
+	hash, ok := secret.Annotations[ContentHashAnnotation]
+	if ok && hash == contentHash(secret) {
+		return
+	}
+	if secret.Labels[ManagedByLabel] != ComponentName {
+		return
+	}
+
+	crypt := c.cryptForSecret(secret)
+	if crypt == nil {
+		return
+	}
+
+	log.Infof("secret %s/%s of crypt %s/%s was modified, restoring it", secret.Namespace, secret.Name, crypt.Namespace, crypt.Name)
+	c.recorder.Eventf(crypt, corev1.EventTypeWarning, SecretDrifted,
+		"Secret %s/%s was modified outside of the controller and will be restored", secret.Namespace, secret.Name)
+	c.enqueueCrypt(crypt)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+func TestDriftedSecretEnqueuesCrypt(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name:   "test-foo-secret",
+		Key:    "test/foo",
+		Labels: map[string]string{"team": "a"},
+	}
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1"},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.UID = "test-crypt-uid"
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.initControllerLists()
+
+	secret := newSecret(map[string][]byte{"foo": []byte("fooSecret")}, secretdef, crypt, "test-ns1")
+	secret.ResourceVersion = "1"
+
+	// an update made by the controller itself is not drift
+	rewritten := secret.DeepCopy()
+	rewritten.ResourceVersion = "2"
+	f.controller.handleSecretUpdate(secret, rewritten)
+	if f.controller.queue.Len() != 0 {
+		t.Fatalf("expected no crypt to be queued, got %d", f.controller.queue.Len())
+	}
+
+	for name, edit := range map[string]func(){
+		"data":       func() { rewritten.Data["foo"] = []byte("tampered") },
+		"type":       func() { rewritten.Type = "kubernetes.io/tls" },
+		"labels":     func() { delete(rewritten.Labels, "team") },
+		"annotation": func() { rewritten.Annotations["note"] = "edited" },
+	} {
+		rewritten = secret.DeepCopy()
+		rewritten.ResourceVersion = "3"
+		edit()
+
+		f.controller.handleSecretUpdate(secret, rewritten)
+
+		key, _ := f.controller.queue.Get()
+		if key != getKey(crypt, t) {
+			t.Errorf("%s: expected %s to be queued, got %v", name, getKey(crypt, t), key)
+		}
+		f.controller.queue.Done(key)
+		f.controller.queue.Forget(key)
+
+		event := <-f.controller.recorder.(*record.FakeRecorder).Events
+		if !strings.Contains(event, SecretDrifted) || !strings.Contains(event, "test-ns1/test-foo-secret") {
+			t.Errorf("%s: unexpected event %q", name, event)
+		}
+	}
+}
+
+func TestUnchangedSecretNotWritten(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+	namespaces := []string{"test-ns1", "test-ns2"}
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: namespaces,
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	for _, ns := range namespaces {
+		f.namespaceLister = append(f.namespaceLister, newNamespace(ns))
+	}
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+
+	// test-ns1 is up to date, while test-ns2 still holds a previous value of the key
+	current := newSecret(obj.GetData(), secretdef, crypt, "test-ns1")
+	current.ResourceVersion = "1"
+	outdated := newSecret(map[string][]byte{"foo": []byte("previous")}, secretdef, crypt, "test-ns2")
+	outdated.ResourceVersion = "2"
+	for _, secret := range []*v1.Secret{current, outdated} {
+		f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+		f.kubeObjects = append(f.kubeObjects, secret)
+	}
+
+	expected := newSecret(obj.GetData(), secretdef, crypt, "test-ns2")
+	expected.ResourceVersion = outdated.ResourceVersion
+	f.expectUpdateSecretAction(expected)
+
+	var results []v1alpha1.SecretStatus
+	for _, ns := range namespaces {
+		results = append(results, secretStatus(secretdef, ns, nil))
+	}
+	f.expectUpdateCryptStatusAction(crypt, results)
+
+	f.run(getKey(crypt, t))
+}
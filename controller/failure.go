@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	pkgerrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// StoreKeyNotFound is used as part of the Event 'reason' when the key of a secret is not in its store
+	StoreKeyNotFound = "StoreKeyNotFound"
+	// StoreUnavailable is used as part of the Event 'reason' when the store of a secret could not be read
+	StoreUnavailable = "StoreUnavailable"
+	// StorePermissionDenied is used as part of the Event 'reason' when the store refuses to let the key of a secret be read
+	StorePermissionDenied = "StorePermissionDenied"
+	// StoreUnauthenticated is used as part of the Event 'reason' when the controller could not authenticate with the
+	// store of a secret
+	StoreUnauthenticated = "StoreUnauthenticated"
+	// InvalidData is used as part of the Event 'reason' when the value of a key could not be decoded into a secret
+	InvalidData = "InvalidData"
+	// SecretWriteFailed is used as part of the Event 'reason' when a secret could not be written to the api server
+	SecretWriteFailed = "SecretWriteFailed"
+)
+
+// writeError is returned when a secret could not be written to the api server, as opposed to read from its store.
+type writeError struct {
+	err error
+}
+
+func (e *writeError) Error() string {
+	return e.err.Error()
+}
+
+// heldError is returned by a sync that left secrets unsynced for errors that retrying will not fix. the sync counts
+// as failed, but the Crypt is not requeued, and the failures stay in its status until the next resync.
+type heldError struct {
+	count int
+}
+
+func (e *heldError) Error() string {
+	return fmt.Sprintf("%d secrets could not be synced and are not retried", e.count)
+}
+
+func isHeld(err error) bool {
+	_, ok := err.(*heldError)
+	return ok
+}
+
+// failureReason returns the Event 'reason' of an error syncing a secret.
+func failureReason(err error) string {
+	switch err.(type) {
+	case *conflictError:
+		return SecretConflict
+	case *writeError:
+		return SecretWriteFailed
+	case *nameCollisionError:
+		return SecretNameCollision
+	}
+
+	switch pkgerrors.Cause(err) {
+	case store.NotFoundError:
+		return StoreKeyNotFound
+	case store.InvalidDataError:
+		return InvalidData
+	case store.PermissionDeniedError:
+		return StorePermissionDenied
+	case store.AuthenticationError:
+		return StoreUnauthenticated
+	}
+	return StoreUnavailable
+}
+
+// isTransient reports whether syncing a secret again may get past an error. conflicts last until the secret is given
+// up or the creation policy changed, name collisions until the keys or the crypt change, and permanent store errors
+// until the key or the store is fixed.
+func isTransient(err error) bool {
+	return isTransientReason(failureReason(err))
+}
+
+// isTransientReason reports whether syncing a secret again may get past a failure of the given reason. the reasons of
+// the errors store.IsPermanent classifies as permanent are not.
+func isTransientReason(reason string) bool {
+	switch reason {
+	case StoreUnavailable, SecretWriteFailed:
+		return true
+	}
+	return false
+}
+
+// failedNamespaces groups the namespaces a secret could not be synced to by the reason of the failure, keeping the
+// first error of each reason.
+type failedNamespaces struct {
+	reasons    []string
+	namespaces map[string][]string
+	errs       map[string]error
+}
+
+func (f *failedNamespaces) add(namespace string, err error) {
+	if f.namespaces == nil {
+		f.namespaces = make(map[string][]string)
+		f.errs = make(map[string]error)
+	}
+
+	reason := failureReason(err)
+	if _, ok := f.errs[reason]; !ok {
+		f.reasons = append(f.reasons, reason)
+		f.errs[reason] = err
+	}
+	f.namespaces[reason] = append(f.namespaces[reason], namespace)
+}
+
+// recordFailures records a Warning event on a Crypt for each reason a secret could not be synced, listing the
+// namespaces it failed in, rather than one event per namespace on every sync.
+func (c *Controller) recordFailures(crypt *v1alpha1.Crypt, def v1alpha1.SecretDefinition, failed failedNamespaces) {
+	for _, reason := range failed.reasons {
+		namespaces := strings.Join(failed.namespaces[reason], ", ")
+		if reason == SecretConflict {
+			c.recorder.Eventf(crypt, corev1.EventTypeWarning, reason,
+				"Secret %s exists in namespaces %s and is not owned by the crypt, set creationPolicy to Adopt or Merge to write it", def.GetName(), namespaces)
+			continue
+		}
+
+		c.recorder.Eventf(crypt, corev1.EventTypeWarning, reason, "Could not sync secret %s from key %s to namespaces %s: %v",
+			def.GetName(), def.GetKey(), namespaces, failed.errs[reason])
+	}
+}
+
+// recordPrefixFailure records a Warning event on a Crypt for a prefix whose keys could not be listed.
+func (c *Controller) recordPrefixFailure(crypt *v1alpha1.Crypt, sec v1alpha1.SecretDefinition, err error) {
+	c.recorder.Eventf(crypt, corev1.EventTypeWarning, failureReason(err), "Could not list keys under prefix %s: %v", sec.GetPrefix(), err)
+}
@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
+
+// failingStore fails every read with err.
+type failingStore struct {
+	err error
+}
+
+func (s *failingStore) Get(ctx context.Context, key string) (store.Object, error) {
+	return nil, s.err
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := map[string]error{
+		SecretConflict:        &conflictError{namespace: "test-ns1", name: "test-foo-secret"},
+		SecretWriteFailed:     &writeError{err: fmt.Errorf("forbidden")},
+		SecretNameCollision:   &nameCollisionError{name: "test-foo-secret", key: "test/foo", other: "test/bar"},
+		StoreKeyNotFound:      errors.Wrap(store.NotFoundError, "crypt/dev/foo"),
+		InvalidData:           store.InvalidDataError,
+		StorePermissionDenied: errors.Wrap(store.PermissionDeniedError, "vault request failed"),
+		StoreUnauthenticated:  errors.Wrap(store.AuthenticationError, "no vault token configured"),
+		StoreUnavailable:      errors.Wrap(store.UnavailableError, "consul request failed"),
+	}
+	for reason, err := range tests {
+		if got := failureReason(err); got != reason {
+			t.Errorf("%v: expected reason %s, got %s", err, reason, got)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{&conflictError{namespace: "test-ns1", name: "test-foo-secret"}, false},
+		{&nameCollisionError{name: "test-foo-secret", key: "test/foo", other: "test/bar"}, false},
+		{errors.Wrap(store.NotFoundError, "crypt/dev/foo"), false},
+		{store.InvalidDataError, false},
+		{errors.Wrap(store.PermissionDeniedError, "vault request failed"), false},
+		{errors.Wrap(store.AuthenticationError, "no vault token configured"), false},
+		{errors.Wrap(store.UnavailableError, "consul request failed"), true},
+		{context.DeadlineExceeded, true},
+		{&writeError{err: fmt.Errorf("forbidden")}, true},
+	}
+	for _, test := range tests {
+		if got := isTransient(test.err); got != test.transient {
+			t.Errorf("%v: expected transient to be %v, got %v", test.err, test.transient, got)
+		}
+	}
+}
+
+func TestPermanentFailuresReported(t *testing.T) {
+	secretdef := v1alpha1.SecretDefinition{Name: "test-foo-secret", Key: "test/foo"}
+	crypt := newCrypt(&cryptOpts{name: "test-crypt", namespace: "default", secrets: []v1alpha1.SecretDefinition{secretdef}})
+
+	missing := secretStatus(secretdef, "test-ns1", errors.Wrap(store.NotFoundError, "test/foo"))
+	unavailable := secretStatus(secretdef, "test-ns2", errors.Wrap(store.UnavailableError, "consul request failed"))
+
+	tests := []struct {
+		results []v1alpha1.SecretStatus
+		reason  string
+		message string
+	}{
+		{
+			results: []v1alpha1.SecretStatus{missing, secretStatus(secretdef, "test-ns2", nil)},
+			reason:  PermanentSyncFailure,
+			message: "1 of 2 secrets could not be synced; StoreKeyNotFound failures are not retried",
+		},
+		{
+			// the outage is retried, and may hide the missing key until it is over
+			results: []v1alpha1.SecretStatus{missing, unavailable},
+			reason:  FailedSync,
+			message: "2 of 2 secrets could not be synced; StoreKeyNotFound failures are not retried",
+		},
+		{
+			results: []v1alpha1.SecretStatus{unavailable},
+			reason:  FailedSync,
+			message: "1 of 1 secrets could not be synced",
+		},
+	}
+	for _, test := range tests {
+		status := cryptStatus(crypt, test.results, testTime)
+		condition := status.GetCondition(v1alpha1.CryptReady)
+		if condition == nil || condition.Reason != test.reason || condition.Message != test.message {
+			t.Errorf("expected a %s condition with message %q, got %+v", test.reason, test.message, condition)
+		}
+	}
+}
+
+func TestTransientFailureRequeued(t *testing.T) {
+	f := newFixture(t)
+
+	unavailable := errors.Wrap(store.UnavailableError, "consul request failed")
+	f.stores.Register(defaultTestStore, &failingStore{err: unavailable})
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1"},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	namespace := newNamespace("test-ns1")
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(secretdef, "test-ns1", unavailable)})
+
+	f.runExpectError(getKey(crypt, t))
+
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, StoreUnavailable) || !strings.Contains(event, "secret test-foo-secret") || !strings.Contains(event, "namespaces test-ns1") {
+		t.Errorf("unexpected event %q", event)
+	}
+	if len(events) > 0 {
+		t.Errorf("unexpected event %q", <-events)
+	}
+}
+
+func TestPermanentFailureNotRequeued(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-missing-secret",
+		Key:  "test/missing",
+	}
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-missing-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1"},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	namespace := newNamespace("test-ns1")
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+	f.initControllerLists()
+
+	key := getKey(crypt, t)
+	f.controller.queue.Add(key)
+	f.controller.processNextWorkItem(context.Background())
+
+	if f.controller.queue.Len() != 0 || f.controller.queue.NumRequeues(key) != 0 {
+		t.Errorf("expected a crypt failing for a missing key not to be requeued")
+	}
+
+	// the sync failed, and does not count as a success
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counted := false
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["crypt"] != key {
+				continue
+			}
+			switch family.GetName() {
+			case "crypt_controller_reconcile_total":
+				counted = true
+				if labels["result"] != metrics.ResultError {
+					t.Errorf("expected the sync to be counted as an error, got %v", labels)
+				}
+			case "crypt_controller_seconds_since_last_success":
+				t.Error("expected no successful sync to be recorded")
+			}
+		}
+	}
+	if !counted {
+		t.Error("expected the sync to be counted")
+	}
+}
+
+func TestFailureRecordedOncePerReason(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-missing-secret",
+		Key:  "test/missing",
+	}
+	namespaces := []string{"test-ns1", "test-ns2", "test-ns3"}
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: namespaces,
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+
+	var results []v1alpha1.SecretStatus
+	for _, ns := range namespaces {
+		namespace := newNamespace(ns)
+		f.namespaceLister = append(f.namespaceLister, namespace)
+		f.kubeObjects = append(f.kubeObjects, namespace)
+		results = append(results, secretStatus(secretdef, ns, store.NotFoundError))
+	}
+	f.expectUpdateCryptStatusAction(crypt, results)
+
+	f.run(getKey(crypt, t))
+
+	// a missing key is reported once for every namespace, not once per namespace
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, StoreKeyNotFound) || !strings.Contains(event, "namespaces test-ns1, test-ns2, test-ns3") {
+		t.Errorf("unexpected event %q", event)
+	}
+	if len(events) > 0 {
+		t.Errorf("unexpected event %q", <-events)
+	}
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	log "k8s.io/klog"
+)
+
+// CryptFinalizer keeps a Crypt around until the secrets it created in every target namespace are deleted. owner
+// references cannot be used for this, as the garbage collector only follows them within a namespace.
+const CryptFinalizer = "core.bluehoodie.io/secrets"
+
+func hasFinalizer(crypt *v1alpha1.Crypt) bool {
+	for _, f := range crypt.Finalizers {
+		if f == CryptFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// addFinalizer returns the updated Crypt, so that the sync can go on with the latest resource version.
+func (c *Controller) addFinalizer(crypt *v1alpha1.Crypt) (*v1alpha1.Crypt, error) {
+	cryptCopy := crypt.DeepCopy()
+	cryptCopy.Finalizers = append(cryptCopy.Finalizers, CryptFinalizer)
+	return c.cryptClientset.CoreV1alpha1().Crypts(crypt.Namespace).Update(cryptCopy)
+}
+
+// finalizeCrypt deletes every secret created for a Crypt that is being deleted, then lets the deletion go on. the
+// secrets of a Crypt with the Orphan creation policy are left in place.
+func (c *Controller) finalizeCrypt(crypt *v1alpha1.Crypt) error {
+	if crypt.Spec.GetCreationPolicy() != v1alpha1.CreationPolicyOrphan {
+		if err := c.deleteSecrets(crypt); err != nil {
+			return err
+		}
+	}
+
+	cryptCopy := crypt.DeepCopy()
+	cryptCopy.Finalizers = nil
+	for _, f := range crypt.Finalizers {
+		if f != CryptFinalizer {
+			cryptCopy.Finalizers = append(cryptCopy.Finalizers, f)
+		}
+	}
+
+	_, err := c.cryptClientset.CoreV1alpha1().Crypts(crypt.Namespace).Update(cryptCopy)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	c.enqueueClaimants(crypt)
+	return nil
+}
+
+// deleteSecrets deletes the secrets created for a Crypt in every namespace.
+func (c *Controller) deleteSecrets(crypt *v1alpha1.Crypt) error {
+	secrets, err := c.ownedSecrets(crypt)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range secrets {
+		log.Infof("deleting secret %s/%s of deleted crypt %s/%s", secret.Namespace, secret.Name, crypt.Namespace, crypt.Name)
+		err := c.kubeClientset.CoreV1().Secrets(secret.Namespace).Delete(secret.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		metrics.SecretOperation(metrics.SecretDeleted)
+	}
+	return nil
+}
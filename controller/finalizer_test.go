@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	core "k8s.io/client-go/testing"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+func (f *fixture) expectUpdateCryptAction(crypt *v1alpha1.Crypt) {
+	f.cryptActions = append(f.cryptActions, core.NewUpdateAction(schema.GroupVersionResource{Resource: "crypts"}, crypt.Namespace, crypt))
+}
+
+// newLegacySecret returns a secret as created by earlier versions of the controller, which only referenced the Crypt
+// as its owner.
+func newLegacySecret(secdef v1alpha1.SecretDefinition, crypt *v1alpha1.Crypt, namespace string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            secdef.GetName(),
+			Namespace:       namespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Crypt", Name: crypt.Name, UID: crypt.UID}},
+		},
+	}
+}
+
+func TestFinalizerAdded(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.Finalizers = nil
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	finalized := crypt.DeepCopy()
+	finalized.Finalizers = []string{CryptFinalizer}
+	f.expectUpdateCryptAction(finalized)
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+	f.expectCreateSecretAction(newSecret(obj.GetData(), secretdef, finalized, namespace.Name))
+	f.expectUpdateCryptStatusAction(finalized, []v1alpha1.SecretStatus{secretStatus(secretdef, namespace.Name, nil)})
+
+	f.run(getKey(crypt, t))
+}
+
+func TestSecretsDeletedOnCryptDeletion(t *testing.T) {
+	f := newFixture(t)
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1", "test-ns2"},
+		secrets:          []v1alpha1.SecretDefinition{{Name: "test-foo-secret", Key: "test/foo"}},
+	})
+	crypt.UID = "test-crypt-uid"
+	now := metav1.Now()
+	crypt.DeletionTimestamp = &now
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+
+	var secrets []*v1.Secret
+	for _, ns := range []string{"test-ns1", "test-ns2"} {
+		secrets = append(secrets, newSecret(nil, crypt.Spec.Secrets[0], crypt, ns))
+	}
+	// secrets of other crypts are left alone
+	other := newSecret(nil, crypt.Spec.Secrets[0], newCrypt(&cryptOpts{name: "other-crypt", namespace: "default"}), "test-ns3")
+	secrets = append(secrets, other)
+	// and secrets created before they were labelled are deleted as well
+	legacy := newLegacySecret(crypt.Spec.Secrets[0], crypt, "test-ns4")
+	secrets = append(secrets, legacy)
+
+	for _, secret := range secrets {
+		f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+	}
+
+	f.expectDeleteSecretAction(secrets[0])
+	f.expectDeleteSecretAction(secrets[1])
+	f.expectDeleteSecretAction(legacy)
+
+	finalized := crypt.DeepCopy()
+	finalized.Finalizers = nil
+	f.expectUpdateCryptAction(finalized)
+
+	f.run(getKey(crypt, t))
+}
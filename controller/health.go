@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog"
+)
+
+const (
+	// StoreProbeInterval is how often the stores are pinged
+	StoreProbeInterval = 30 * time.Second
+
+	// DefaultStuckSyncTimeout is how long a worker may sync a single Crypt before it is considered stuck
+	DefaultStuckSyncTimeout = 10 * time.Minute
+)
+
+// InformersSynced returns an error until every informer of the controller has synced.
+func (c *Controller) InformersSynced() error {
+	synced := map[string]func() bool{
+		"namespaces":          c.namespaceInformerSynced,
+		"secrets":             c.secretInformerSynced,
+		"crypts":              c.cryptInformerSynced,
+		"secretstores":        c.secretStoreInformerSynced,
+		"clustersecretstores": c.clusterSecretStoreInformerSynced,
+	}
+
+	var errs []error
+	for name, hasSynced := range synced {
+		if !hasSynced() {
+			errs = append(errs, fmt.Errorf("%s informer has not synced", name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// WorkersAlive returns an error when a worker has been syncing the same Crypt for longer than the stuck sync
+// timeout, which is more than its reads from the stores can take.
+func (c *Controller) WorkersAlive() error {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	now := time.Now()
+	var errs []error
+	for key, start := range c.syncing {
+		if d := now.Sub(start); d > c.stuckSyncTimeout {
+			errs = append(errs, fmt.Errorf("worker has been syncing %s for %v", key, d.Round(time.Second)))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// StoresReachable returns the errors of the last ping of the stores configured at startup that could not be
+// reached. stores described by SecretStores and ClusterSecretStores belong to their tenants, and are reported on
+// their resource rather than taking every replica out of service.
+func (c *Controller) StoresReachable() error {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	var errs []error
+	for _, name := range c.stores.Names() {
+		if isResourceStore(name) {
+			continue
+		}
+		if err, ok := c.pingErrors[name]; ok {
+			errs = append(errs, fmt.Errorf("store %q: %v", name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c *Controller) startSync(key string) {
+	c.healthMu.Lock()
+	c.syncing[key] = time.Now()
+	c.healthMu.Unlock()
+}
+
+func (c *Controller) endSync(key string) {
+	c.healthMu.Lock()
+	delete(c.syncing, key)
+	c.healthMu.Unlock()
+}
+
+// StartProbes pings the stores every StoreProbeInterval until stopCh is closed. it does not depend on Run, so that
+// standby replicas report whether they could reach the stores before they lead.
+func (c *Controller) StartProbes(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	go wait.Until(func() { c.probeStores(ctx) }, StoreProbeInterval, stopCh)
+}
+
+// probeStores pings every store that can be pinged, and keeps the errors of those that could not be reached.
+func (c *Controller) probeStores(ctx context.Context) {
+	pingErrors := make(map[string]error)
+	for _, name := range c.stores.Names() {
+		st, err := c.stores.Get(name)
+		if err != nil {
+			continue
+		}
+		pinger, ok := st.(store.Pinger)
+		if !ok {
+			continue
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, c.storeTimeout)
+		err = pinger.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			log.Warningf("could not reach store %q: %v", name, err)
+			pingErrors[name] = err
+		}
+	}
+
+	c.healthMu.Lock()
+	previous := c.pingErrors
+	c.pingErrors = pingErrors
+	c.healthMu.Unlock()
+
+	c.recordStoreReachability(previous, pingErrors)
+}
+
+// recordStoreReachability records an event on the SecretStores and ClusterSecretStores that could no longer be
+// reached since the previous ping, or could be reached again.
+func (c *Controller) recordStoreReachability(previous, current map[string]error) {
+	for name, err := range current {
+		if _, ok := previous[name]; ok || !isResourceStore(name) {
+			continue
+		}
+		if obj := c.storeResource(name); obj != nil {
+			c.recorder.Eventf(obj, corev1.EventTypeWarning, StoreUnreachable, "Could not reach store: %v", err)
+		}
+	}
+
+	for name := range previous {
+		if _, ok := current[name]; ok || !isResourceStore(name) {
+			continue
+		}
+		// stores removed since are not reachable again
+		if _, err := c.stores.Get(name); err != nil {
+			continue
+		}
+		if obj := c.storeResource(name); obj != nil {
+			c.recorder.Event(obj, corev1.EventTypeNormal, StoreReachable, "Store is reachable again")
+		}
+	}
+}
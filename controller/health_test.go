@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+)
+
+// pingingStore fails its pings with err.
+type pingingStore struct {
+	store.Store
+	err error
+}
+
+func (s *pingingStore) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func TestInformersSynced(t *testing.T) {
+	f := newFixture(t)
+
+	if err := f.controller.InformersSynced(); err != nil {
+		t.Fatalf("expected synced informers, got %v", err)
+	}
+
+	f.controller.secretInformerSynced = func() bool { return false }
+	if err := f.controller.InformersSynced(); err == nil || !strings.Contains(err.Error(), "secrets") {
+		t.Errorf("expected the secrets informer not to be synced, got %v", err)
+	}
+}
+
+func TestWorkersAlive(t *testing.T) {
+	f := newFixture(t)
+	f.controller.stuckSyncTimeout = time.Minute
+
+	f.controller.startSync("default/test-crypt")
+	if err := f.controller.WorkersAlive(); err != nil {
+		t.Fatalf("expected live workers, got %v", err)
+	}
+
+	f.controller.syncing["default/test-crypt"] = time.Now().Add(-2 * time.Minute)
+	if err := f.controller.WorkersAlive(); err == nil || !strings.Contains(err.Error(), "default/test-crypt") {
+		t.Errorf("expected a stuck worker, got %v", err)
+	}
+
+	f.controller.endSync("default/test-crypt")
+	if err := f.controller.WorkersAlive(); err != nil {
+		t.Errorf("expected live workers, got %v", err)
+	}
+}
+
+func TestStoresReachable(t *testing.T) {
+	f := newFixture(t)
+
+	// the memory stores of the fixture cannot be pinged, and are not reported
+	f.stores.Register(otherTestStore, &pingingStore{Store: f.store, err: fmt.Errorf("connection refused")})
+
+	f.controller.probeStores(context.Background())
+	err := f.controller.StoresReachable()
+	if err == nil || !strings.Contains(err.Error(), otherTestStore) || strings.Contains(err.Error(), `"`+defaultTestStore+`"`) {
+		t.Fatalf("expected only the %s store to be unreachable, got %v", otherTestStore, err)
+	}
+
+	f.stores.Register(otherTestStore, &pingingStore{Store: f.store})
+	f.controller.probeStores(context.Background())
+	if err := f.controller.StoresReachable(); err != nil {
+		t.Errorf("expected reachable stores, got %v", err)
+	}
+}
+
+func TestStoresProbedWithoutRun(t *testing.T) {
+	f := newFixture(t)
+	f.stores.Register(otherTestStore, &pingingStore{Store: f.store, err: fmt.Errorf("connection refused")})
+
+	// standby replicas never call Run, and must still report the stores they cannot reach
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.controller.StartProbes(stopCh)
+
+	err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return f.controller.StoresReachable() != nil, nil
+	})
+	if err != nil {
+		t.Errorf("expected the %s store to be reported unreachable", otherTestStore)
+	}
+}
+
+func TestSecretStoreReachabilityReportedAsEvents(t *testing.T) {
+	f := newFixture(t)
+
+	secretStore := &v1alpha1.SecretStore{ObjectMeta: metav1.ObjectMeta{Name: otherTestStore, Namespace: "team-a"}}
+	f.cryptInformer.Core().V1alpha1().SecretStores().Informer().GetIndexer().Add(secretStore)
+	key := secretStoreKey("team-a", otherTestStore)
+	f.stores.Register(key, &pingingStore{Store: f.store, err: fmt.Errorf("connection refused")})
+
+	// a tenant's store does not make the replicas unready
+	f.controller.probeStores(context.Background())
+	if err := f.controller.StoresReachable(); err != nil {
+		t.Fatalf("expected the SecretStore to be left out of readiness, got %v", err)
+	}
+
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, StoreUnreachable) || !strings.Contains(event, "connection refused") {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// the store is only reported again once it can be reached
+	f.controller.probeStores(context.Background())
+	f.stores.Register(key, &pingingStore{Store: f.store})
+	f.controller.probeStores(context.Background())
+	if event := <-events; !strings.Contains(event, StoreReachable) {
+		t.Errorf("unexpected event %q", event)
+	}
+	if len(events) > 0 {
+		t.Errorf("unexpected event %q", <-events)
+	}
+}
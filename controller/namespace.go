@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceMatcher tells whether a namespace is targeted by a Crypt: its name matches one of the patterns or its
+// labels match the selector, and its name matches none of the exclusions.
+type namespaceMatcher struct {
+	patterns   []func(name string) bool
+	selector   labels.Selector
+	exclusions []func(name string) bool
+}
+
+// newNamespaceMatcher compiles the namespace patterns of a Crypt once, and reports every pattern that is invalid.
+func newNamespaceMatcher(crypt *v1alpha1.Crypt) (*namespaceMatcher, error) {
+	m := &namespaceMatcher{selector: labels.Nothing()}
+	var errs []error
+
+	for _, pattern := range crypt.Spec.Namespaces {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid namespace pattern %q: %v", pattern, err))
+			continue
+		}
+		m.patterns = append(m.patterns, re.MatchString)
+	}
+
+	for _, pattern := range crypt.Spec.NamespacePatterns {
+		match, err := pattern.Matcher()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.patterns = append(m.patterns, match)
+	}
+
+	for _, pattern := range crypt.Spec.ExcludeNamespaces {
+		match, err := pattern.Matcher()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.exclusions = append(m.exclusions, match)
+	}
+
+	if crypt.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(crypt.Spec.NamespaceSelector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid namespace selector: %v", err))
+		} else {
+			m.selector = selector
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return m, nil
+}
+
+func (m *namespaceMatcher) matches(namespace *corev1.Namespace) bool {
+	for _, match := range m.exclusions {
+		if match(namespace.Name) {
+			return false
+		}
+	}
+
+	if m.selector.Matches(labels.Set(namespace.Labels)) {
+		return true
+	}
+	for _, match := range m.patterns {
+		if match(namespace.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetNamespaces returns the sorted names of the namespaces matched for a Crypt.
+func (c *Controller) targetNamespaces(matcher *namespaceMatcher) ([]string, error) {
+	namespaces, err := c.namespaceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, ns := range namespaces {
+		if matcher.matches(ns) {
+			result = append(result, ns.Name)
+		}
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// enqueueCryptsForNamespace queues the Crypts targeting any of the given namespaces. the patterns of a Crypt are
+// compiled once per generation, and crypts with an invalid spec target no namespace.
+func (c *Controller) enqueueCryptsForNamespace(namespaces ...*corev1.Namespace) {
+	crypts, err := c.cryptLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+
+	for _, crypt := range crypts {
+		key, err := cache.MetaNamespaceKeyFunc(crypt)
+		if err != nil {
+			continue
+		}
+		matcher := c.claimantFor(key, crypt).matcher
+		if matcher == nil {
+			continue
+		}
+		for _, ns := range namespaces {
+			if matcher.matches(ns) {
+				c.enqueueCrypt(crypt)
+				break
+			}
+		}
+	}
+}
+
+func (c *Controller) handleNamespaceUpdate(old, new interface{}) {
+	oldNamespace, ok := old.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	newNamespace, ok := new.(*corev1.Namespace)
+	if !ok || labels.Equals(oldNamespace.Labels, newNamespace.Labels) {
+		return
+	}
+
+	// crypts that no longer target the namespace are synced as well, so that they prune their secrets from it
+	c.enqueueCryptsForNamespace(oldNamespace, newNamespace)
+}
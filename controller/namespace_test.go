@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/validation"
+)
+
+func newLabelledNamespace(name string, labels map[string]string) *v1.Namespace {
+	ns := newNamespace(name)
+	ns.Labels = labels
+	return ns
+}
+
+func TestSecretsCreatedInSelectedNamespaces(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"^shared$"},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}
+	crypt.Spec.ExcludeNamespaces = []v1alpha1.NamespacePattern{{Pattern: "*-sandbox"}}
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+
+	for _, ns := range []*v1.Namespace{
+		newLabelledNamespace("payments", map[string]string{"team": "payments"}),
+		newLabelledNamespace("payments-sandbox", map[string]string{"team": "payments"}),
+		newLabelledNamespace("search", map[string]string{"team": "search"}),
+		newNamespace("shared"),
+	} {
+		f.namespaceLister = append(f.namespaceLister, ns)
+		f.kubeObjects = append(f.kubeObjects, ns)
+	}
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+
+	var results []v1alpha1.SecretStatus
+	for _, ns := range []string{"payments", "shared"} {
+		f.expectCreateSecretAction(newSecret(obj.GetData(), secretdef, crypt, ns))
+		results = append(results, secretStatus(secretdef, ns, nil))
+	}
+	f.expectUpdateCryptStatusAction(crypt, results)
+
+	f.run(getKey(crypt, t))
+}
+
+func TestInvalidNamespaceSelector(t *testing.T) {
+	f := newFixture(t)
+
+	crypt := newCrypt(&cryptOpts{
+		name:      "test-crypt",
+		namespace: "default",
+		secrets:   []v1alpha1.SecretDefinition{{Name: "test-foo-secret", Key: "test/foo"}},
+	})
+	crypt.Spec.NamespaceSelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Near"}},
+	}
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+
+	// the sync stops before any secret is written or pruned
+	f.expectUpdateCryptInvalidStatusAction(crypt, InvalidSpec, validation.ValidateCrypt(crypt).ToAggregate())
+
+	f.run(getKey(crypt, t))
+}
+
+func TestInvalidNamespacePatterns(t *testing.T) {
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"dev-(", "^test-"},
+	})
+	crypt.Spec.NamespacePatterns = []v1alpha1.NamespacePattern{
+		{Pattern: "dev-[", MatchType: v1alpha1.NamespaceMatchGlob},
+		{Pattern: "dev-*", MatchType: "Fuzzy"},
+		{Pattern: "prod", MatchType: v1alpha1.NamespaceMatchExact},
+	}
+
+	_, err := newNamespaceMatcher(crypt)
+	if err == nil {
+		t.Fatal("expected invalid patterns to be reported")
+	}
+	for _, pattern := range []string{`"dev-("`, `"dev-["`, `"dev-*"`} {
+		if !strings.Contains(err.Error(), pattern) {
+			t.Errorf("expected pattern %s to be reported in %q", pattern, err)
+		}
+	}
+	if strings.Contains(err.Error(), `"prod"`) || strings.Contains(err.Error(), `"^test-"`) {
+		t.Errorf("expected only invalid patterns to be reported in %q", err)
+	}
+}
+
+func TestNamespacePatternMatchTypes(t *testing.T) {
+	tests := []struct {
+		pattern  v1alpha1.NamespacePattern
+		matching []string
+		other    []string
+	}{
+		{
+			pattern:  v1alpha1.NamespacePattern{Pattern: "dev-*"},
+			matching: []string{"dev-", "dev-app"},
+			other:    []string{"devtools", "prod-dev-app"},
+		},
+		{
+			pattern:  v1alpha1.NamespacePattern{Pattern: "dev-[0-9]?", MatchType: v1alpha1.NamespaceMatchGlob},
+			matching: []string{"dev-1a"},
+			other:    []string{"dev-1", "dev-a1"},
+		},
+		{
+			pattern:  v1alpha1.NamespacePattern{Pattern: "dev|test-.+", MatchType: v1alpha1.NamespaceMatchRegex},
+			matching: []string{"dev", "test-app"},
+			other:    []string{"devtools", "prod-dev", "test-"},
+		},
+		{
+			pattern:  v1alpha1.NamespacePattern{Pattern: "dev-*", MatchType: v1alpha1.NamespaceMatchExact},
+			matching: []string{"dev-*"},
+			other:    []string{"dev-app"},
+		},
+	}
+
+	for _, test := range tests {
+		match, err := test.pattern.Matcher()
+		if err != nil {
+			t.Errorf("unexpected error compiling %+v: %v", test.pattern, err)
+			continue
+		}
+		for _, name := range test.matching {
+			if !match(name) {
+				t.Errorf("expected %+v to match %q", test.pattern, name)
+			}
+		}
+		for _, name := range test.other {
+			if match(name) {
+				t.Errorf("expected %+v not to match %q", test.pattern, name)
+			}
+		}
+	}
+}
+
+func TestCryptsEnqueuedOnNamespaceRelabel(t *testing.T) {
+	f := newFixture(t)
+
+	selected := newCrypt(&cryptOpts{name: "selected", namespace: "default"})
+	selected.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}
+	other := newCrypt(&cryptOpts{name: "other", namespace: "default", targetNamespaces: []string{"^search$"}})
+
+	f.cryptLister = append(f.cryptLister, selected, other)
+	f.initControllerLists()
+
+	old := newNamespace("payments")
+	relabelled := newLabelledNamespace("payments", map[string]string{"team": "payments"})
+	relabelled.ResourceVersion = "2"
+
+	f.controller.handleNamespaceUpdate(old, relabelled)
+
+	got := make(chan interface{})
+	go func() {
+		key, _ := f.controller.queue.Get()
+		got <- key
+	}()
+
+	select {
+	case key := <-got:
+		if key != getKey(selected, t) {
+			t.Errorf("expected %s to be queued, got %v", getKey(selected, t), key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crypt was not queued after the namespace was relabelled")
+	}
+	if f.controller.queue.Len() != 0 {
+		t.Errorf("expected only the crypt selecting the namespace to be queued, %d more queued", f.controller.queue.Len())
+	}
+
+	// the matchers are kept for the next namespace event rather than compiled again
+	cl := f.controller.claimants[getKey(other, t)]
+	if cl == nil || cl.matcher == nil {
+		t.Fatal("expected the matcher of the crypt to be cached")
+	}
+	f.controller.handleNamespaceUpdate(relabelled, old)
+	if f.controller.claimantFor(getKey(other, t), other) != cl {
+		t.Error("expected the cached matcher to be reused")
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	log "k8s.io/klog"
+)
+
+// SecretConflict is used as part of the Event 'reason' when a secret of a Crypt cannot be written because a secret of
+// the same name is not owned by the Crypt
+const SecretConflict = "SecretConflict"
+
+// conflictError is returned when a target namespace holds a secret that the Crypt does not own, and its creation
+// policy does not allow taking it over.
+type conflictError struct {
+	namespace string
+	name      string
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("secret %s/%s already exists and is not owned by the crypt", e.namespace, e.name)
+}
+
+func isConflict(err error) bool {
+	_, ok := err.(*conflictError)
+	return ok
+}
+
+// ownsSecret tells whether a secret was created for a Crypt. secrets created by earlier versions of the controller
+// only carry an owner reference to it.
+func ownsSecret(crypt *v1alpha1.Crypt, secret *corev1.Secret) bool {
+	if secret.Labels[ManagedByLabel] == ComponentName && secret.Labels[CryptUIDLabel] == string(crypt.UID) {
+		return true
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID == crypt.UID && ref.Kind == "Crypt" {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSecret writes secret over the live secret of the same name, following the creation policy of the Crypt
+// when the live secret is not owned by it.
+func (c *Controller) updateSecret(crypt *v1alpha1.Crypt, sec v1alpha1.SecretDefinition, live, secret *corev1.Secret) (*corev1.Secret, error) {
+	if owner := c.cryptForSecret(live); owner != nil && owner.UID != crypt.UID && precedes(crypt, owner) {
+		// the crypt that wrote the secret leaves it to this one
+		log.Infof("taking secret %s/%s over from crypt %s/%s", live.Namespace, live.Name, owner.Namespace, owner.Name)
+	} else if !ownsSecret(crypt, live) {
+		switch crypt.Spec.GetCreationPolicy() {
+		case v1alpha1.CreationPolicyAdopt:
+			log.Infof("adopting secret %s/%s for crypt %s/%s", live.Namespace, live.Name, crypt.Namespace, crypt.Name)
+		case v1alpha1.CreationPolicyMerge:
+			return c.mergeSecret(sec, live, secret.Data)
+		default:
+			return nil, &conflictError{namespace: live.Namespace, name: live.Name}
+		}
+	}
+
+	// the secret already holds what would be written, as long as nobody changed it since the controller did
+	if live.Annotations[ContentHashAnnotation] == secret.Annotations[ContentHashAnnotation] &&
+		contentHash(live) == secret.Annotations[ContentHashAnnotation] {
+		metrics.SecretOperation(metrics.SecretUnchanged)
+		return live, nil
+	}
+
+	secret.ResourceVersion = live.ResourceVersion
+	return c.writeUpdate(secret)
+}
+
+// mergeSecret writes data, along with the labels and annotations of the secret definition, into a secret that is not
+// owned by the Crypt. its other keys are kept and it is left unlabelled, so it is never pruned nor deleted.
+func (c *Controller) mergeSecret(sec v1alpha1.SecretDefinition, live *corev1.Secret, data map[string][]byte) (*corev1.Secret, error) {
+	merged := live.DeepCopy()
+	changed := false
+
+	if merged.Data == nil {
+		merged.Data = make(map[string][]byte, len(data))
+	}
+	for k, v := range data {
+		if current, ok := merged.Data[k]; !ok || !bytes.Equal(current, v) {
+			merged.Data[k] = v
+			changed = true
+		}
+	}
+
+	if len(sec.GetLabels()) > 0 && merged.Labels == nil {
+		merged.Labels = make(map[string]string, len(sec.GetLabels()))
+	}
+	for k, v := range sec.GetLabels() {
+		if current, ok := merged.Labels[k]; !ok || current != v {
+			merged.Labels[k] = v
+			changed = true
+		}
+	}
+
+	if len(sec.GetAnnotations()) > 0 && merged.Annotations == nil {
+		merged.Annotations = make(map[string]string, len(sec.GetAnnotations()))
+	}
+	for k, v := range sec.GetAnnotations() {
+		if current, ok := merged.Annotations[k]; !ok || current != v {
+			merged.Annotations[k] = v
+			changed = true
+		}
+	}
+
+	if !changed {
+		metrics.SecretOperation(metrics.SecretUnchanged)
+		return live, nil
+	}
+	return c.writeUpdate(merged)
+}
+
+func (c *Controller) writeUpdate(secret *corev1.Secret) (*corev1.Secret, error) {
+	result, err := c.kubeClientset.CoreV1().Secrets(secret.Namespace).Update(secret)
+	if err != nil {
+		return nil, &writeError{err: err}
+	}
+
+	metrics.SecretOperation(metrics.SecretUpdated)
+	return result, nil
+}
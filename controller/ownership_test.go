@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+// newUnownedSecret returns a secret that was not created by the controller.
+func newUnownedSecret(namespace, name string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			ResourceVersion: "1",
+			Labels:          map[string]string{"team": "b"},
+		},
+		Data: map[string][]byte{"other": []byte("otherSecret")},
+		Type: v1.SecretTypeOpaque,
+	}
+}
+
+func TestCreationPolicies(t *testing.T) {
+	secretdef := v1alpha1.SecretDefinition{
+		Name:   "test-foo-secret",
+		Key:    "test/foo",
+		Labels: map[string]string{"app": "foo"},
+	}
+
+	tests := map[v1alpha1.CreationPolicy]struct {
+		// expected returns the secret written over the unowned secret, if any
+		expected func(crypt *v1alpha1.Crypt, live *v1.Secret, data map[string][]byte) *v1.Secret
+		conflict bool
+	}{
+		"":                            {conflict: true},
+		v1alpha1.CreationPolicyOwner:  {conflict: true},
+		v1alpha1.CreationPolicyOrphan: {conflict: true},
+		v1alpha1.CreationPolicyAdopt: {
+			expected: func(crypt *v1alpha1.Crypt, live *v1.Secret, data map[string][]byte) *v1.Secret {
+				secret := newSecret(data, secretdef, crypt, live.Namespace)
+				secret.ResourceVersion = live.ResourceVersion
+				return secret
+			},
+		},
+		v1alpha1.CreationPolicyMerge: {
+			expected: func(crypt *v1alpha1.Crypt, live *v1.Secret, data map[string][]byte) *v1.Secret {
+				secret := live.DeepCopy()
+				for k, v := range data {
+					secret.Data[k] = v
+				}
+				secret.Labels["app"] = "foo"
+				return secret
+			},
+		},
+	}
+
+	for policy, test := range tests {
+		t.Run(string(policy), func(t *testing.T) {
+			f := newFixture(t)
+
+			crypt := newCrypt(&cryptOpts{
+				name:             "test-crypt",
+				namespace:        "default",
+				targetNamespaces: []string{"test-ns1"},
+				secrets:          []v1alpha1.SecretDefinition{secretdef},
+			})
+			crypt.UID = "test-crypt-uid"
+			crypt.Spec.CreationPolicy = policy
+
+			f.cryptLister = append(f.cryptLister, crypt)
+			f.cryptObjects = append(f.cryptObjects, crypt)
+			f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+
+			live := newUnownedSecret("test-ns1", secretdef.Name)
+			f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(live)
+			f.kubeObjects = append(f.kubeObjects, live)
+
+			obj, _ := f.store.Get(context.Background(), secretdef.Key)
+
+			var err error
+			if test.conflict {
+				err = &conflictError{namespace: "test-ns1", name: secretdef.Name}
+			} else {
+				f.expectUpdateSecretAction(test.expected(crypt, live, obj.GetData()))
+			}
+			f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(secretdef, "test-ns1", err)})
+
+			f.run(getKey(crypt, t))
+
+			events := f.controller.recorder.(*record.FakeRecorder).Events
+			if test.conflict {
+				event := <-events
+				if !strings.Contains(event, SecretConflict) || !strings.Contains(event, "Secret test-foo-secret exists in namespaces test-ns1") {
+					t.Errorf("unexpected event %q", event)
+				}
+				if len(events) > 0 {
+					t.Errorf("unexpected event %q", <-events)
+				}
+				return
+			}
+			if event := <-events; !strings.Contains(event, SuccessSynced) {
+				t.Errorf("unexpected event %q", event)
+			}
+		})
+	}
+}
+
+func TestMergedSecretNotRewritten(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1"},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.UID = "test-crypt-uid"
+	crypt.Spec.CreationPolicy = v1alpha1.CreationPolicyMerge
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("test-ns1"))
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+
+	live := newUnownedSecret("test-ns1", secretdef.Name)
+	for k, v := range obj.GetData() {
+		live.Data[k] = v
+	}
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(live)
+	f.kubeObjects = append(f.kubeObjects, live)
+
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(secretdef, "test-ns1", nil)})
+
+	f.run(getKey(crypt, t))
+}
+
+func TestLegacySecretOwnedThroughOwnerReference(t *testing.T) {
+	crypt := newCrypt(&cryptOpts{name: "test-crypt", namespace: "test-ns1"})
+	crypt.UID = "test-crypt-uid"
+
+	secret := newUnownedSecret("test-ns1", "test-foo-secret")
+	if ownsSecret(crypt, secret) {
+		t.Fatal("expected an unlabelled secret not to be owned")
+	}
+
+	secret.OwnerReferences = []metav1.OwnerReference{{Kind: "Crypt", Name: crypt.Name, UID: crypt.UID}}
+	if !ownsSecret(crypt, secret) {
+		t.Error("expected a secret with an owner reference to the crypt to be owned")
+	}
+}
+
+func TestOrphanedSecretsKeptOnCryptDeletion(t *testing.T) {
+	f := newFixture(t)
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{"test-ns1"},
+		secrets:          []v1alpha1.SecretDefinition{{Name: "test-foo-secret", Key: "test/foo"}},
+	})
+	crypt.UID = "test-crypt-uid"
+	crypt.Spec.CreationPolicy = v1alpha1.CreationPolicyOrphan
+	now := metav1.Now()
+	crypt.DeletionTimestamp = &now
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(newSecret(nil, crypt.Spec.Secrets[0], crypt, "test-ns1"))
+
+	finalized := crypt.DeepCopy()
+	finalized.Finalizers = nil
+	f.expectUpdateCryptAction(finalized)
+
+	f.run(getKey(crypt, t))
+}
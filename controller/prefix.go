@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PrefixAnnotation is set on the secrets created for the keys found under a prefix, to that prefix.
+// It tells which secrets to keep when the keys under their prefix could not be listed.
+const PrefixAnnotation = "core.bluehoodie.io/prefix"
+
+// SecretNameCollision is used as part of the Event 'reason' when a key under a prefix maps to the name of another
+// secret of the same Crypt
+const SecretNameCollision = "SecretNameCollision"
+
+// nameCollisionError is returned for a key under a prefix whose secret name is already used by another key of the
+// Crypt, either defined explicitly or found under an earlier prefix.
+type nameCollisionError struct {
+	name  string
+	key   string
+	other string
+}
+
+func (e *nameCollisionError) Error() string {
+	return fmt.Sprintf("secret name %s of key %s is already used by key %s of the crypt", e.name, e.key, e.other)
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// nameTemplateData is what a secret definition's NameTemplate is rendered with.
+type nameTemplateData struct {
+	// Key is the full key in the store.
+	Key string
+	// Path is the key relative to the prefix.
+	Path string
+	// Base is the last element of the key.
+	Base string
+}
+
+var nameTemplateFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"replace": func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"dnsName": dnsName,
+}
+
+// dnsName turns a key into a valid secret name, replacing the characters a name cannot contain with dashes.
+func dnsName(s string) string {
+	s = invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(s, "-.")
+}
+
+// expandPrefix lists the keys under the prefix of a secret definition and returns a definition for each of them.
+func (c *Controller) expandPrefix(ctx context.Context, sec v1alpha1.SecretDefinition, crypt *v1alpha1.Crypt) ([]v1alpha1.SecretDefinition, error) {
+	st, err := c.storeFor(sec, crypt)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := st.(store.Lister)
+	if !ok {
+		return nil, fmt.Errorf("store does not support listing keys under prefix %s", sec.GetPrefix())
+	}
+
+	prefix := strings.TrimSuffix(sec.GetPrefix(), "/") + "/"
+	ctx, cancel := context.WithTimeout(ctx, c.storeTimeout)
+	defer cancel()
+
+	keys, err := lister.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	nameTemplate := sec.GetNameTemplate()
+	if nameTemplate == "" {
+		nameTemplate = "{{ .Path | dnsName }}"
+	}
+	tmpl, err := template.New("name").Funcs(nameTemplateFuncs).Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %v", err)
+	}
+
+	var defs []v1alpha1.SecretDefinition
+	names := make(map[string]string)
+	for _, key := range keys {
+		data := nameTemplateData{
+			Key:  key,
+			Path: strings.TrimPrefix(strings.TrimPrefix(key, "/"), strings.TrimPrefix(prefix, "/")),
+			Base: path.Base(key),
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("could not render secret name for key %s: %v", key, err)
+		}
+
+		name := strings.TrimSpace(buf.String())
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid secret name %q for key %s: %s", name, key, strings.Join(errs, ", "))
+		}
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("keys %s and %s both map to secret name %s", other, key, name)
+		}
+		names[name] = key
+
+		def := sec
+		def.Name = name
+		def.Key = key
+		def.Prefix = ""
+		def.NameTemplate = ""
+		def.Annotations = make(map[string]string, len(sec.Annotations)+1)
+		for k, v := range sec.Annotations {
+			def.Annotations[k] = v
+		}
+		def.Annotations[PrefixAnnotation] = sec.GetPrefix()
+
+		defs = append(defs, def)
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+func TestSecretsCreatedFromPrefix(t *testing.T) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Prefix:       "test/",
+		NameTemplate: "prefixed-{{ .Base }}",
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.UID = "test-crypt-uid"
+
+	// a secret created for a key that has since been removed from the store
+	stale := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "prefixed-baz",
+			Namespace:   namespace.Name,
+			Labels:      map[string]string{CryptUIDLabel: string(crypt.UID), ManagedByLabel: ComponentName},
+			Annotations: map[string]string{PrefixAnnotation: secretdef.Prefix},
+		},
+	}
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(stale)
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	var results []v1alpha1.SecretStatus
+	for _, name := range []string{"bar", "foo"} {
+		def := v1alpha1.SecretDefinition{
+			Name:        "prefixed-" + name,
+			Key:         "test/" + name,
+			Annotations: map[string]string{PrefixAnnotation: secretdef.Prefix},
+		}
+		obj, _ := f.store.Get(context.Background(), def.Key)
+		f.expectCreateSecretAction(newSecret(obj.GetData(), def, crypt, namespace.Name))
+		results = append(results, secretStatus(def, namespace.Name, nil))
+	}
+	f.expectDeleteSecretAction(stale)
+	f.expectUpdateCryptStatusAction(crypt, results)
+
+	f.run(getKey(crypt, t))
+}
+
+func TestPrefixedNameCollidesWithDefinedName(t *testing.T) {
+	f := newFixture(t)
+
+	prefixdef := v1alpha1.SecretDefinition{
+		Prefix:       "test/",
+		NameTemplate: "prefixed-{{ .Base }}",
+	}
+	// defined after the prefix, and still wins the name of the secret created for test/bar
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "prefixed-bar",
+		Key:  "test/foo",
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{prefixdef, secretdef},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	annotations := map[string]string{PrefixAnnotation: prefixdef.Prefix}
+	collided := v1alpha1.SecretDefinition{Name: "prefixed-bar", Key: "test/bar", Annotations: annotations}
+	prefixed := v1alpha1.SecretDefinition{Name: "prefixed-foo", Key: "test/foo", Annotations: annotations}
+	collision := &nameCollisionError{name: "prefixed-bar", key: "test/bar", other: "test/foo"}
+
+	obj, _ := f.store.Get(context.Background(), "test/foo")
+	f.expectCreateSecretAction(newSecret(obj.GetData(), prefixed, crypt, namespace.Name))
+	f.expectCreateSecretAction(newSecret(obj.GetData(), secretdef, crypt, namespace.Name))
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{
+		secretStatus(collided, namespace.Name, collision),
+		secretStatus(prefixed, namespace.Name, nil),
+		secretStatus(secretdef, namespace.Name, nil),
+	})
+
+	f.run(getKey(crypt, t))
+
+	events := f.controller.recorder.(*record.FakeRecorder).Events
+	if event := <-events; !strings.Contains(event, SecretNameCollision) || !strings.Contains(event, "secret prefixed-bar") || !strings.Contains(event, "namespaces test-ns1") {
+		t.Errorf("unexpected event %q", event)
+	}
+}
+
+func TestDNSName(t *testing.T) {
+	tests := map[string]string{
+		"foo":            "foo",
+		"dev/Foo_Bar":    "dev-foo-bar",
+		"/team/app.conf": "team-app.conf",
+	}
+
+	for in, expected := range tests {
+		if got := dnsName(in); got != expected {
+			t.Errorf("dnsName(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
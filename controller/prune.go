@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"sort"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	log "k8s.io/klog"
+)
+
+// ownedSecrets returns the secrets the controller created for a Crypt, in every namespace, including those created
+// by earlier versions of the controller, which only carry an owner reference to it.
+func (c *Controller) ownedSecrets(crypt *v1alpha1.Crypt) ([]*corev1.Secret, error) {
+	objs, err := c.secretIndexer.ByIndex(cryptUIDIndex, string(crypt.UID))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]*corev1.Secret, 0, len(objs))
+	for _, obj := range objs {
+		if secret, ok := obj.(*corev1.Secret); ok && ownsSecret(crypt, secret) {
+			secrets = append(secrets, secret)
+		}
+	}
+	sortSecrets(secrets)
+	return secrets, nil
+}
+
+// pruneSecrets deletes the secrets created for a Crypt that are not in the desired set of namespace/name pairs,
+// because their definition was removed or renamed, or their namespace no longer matches. secrets created for a
+// prefix whose keys could not be listed are kept, as there is no telling which of them are still wanted.
+func (c *Controller) pruneSecrets(crypt *v1alpha1.Crypt, desired map[string]struct{}, unlistedPrefixes map[string]struct{}) {
+	secrets, err := c.ownedSecrets(crypt)
+	if err != nil {
+		return
+	}
+
+	for _, secret := range secrets {
+		if _, ok := desired[secret.Namespace+"/"+secret.Name]; ok {
+			continue
+		}
+		if prefix, ok := secret.Annotations[PrefixAnnotation]; ok {
+			if _, unlisted := unlistedPrefixes[prefix]; unlisted {
+				continue
+			}
+		}
+
+		log.Infof("deleting secret %s/%s no longer defined by crypt %s/%s", secret.Namespace, secret.Name, crypt.Namespace, crypt.Name)
+		err := c.kubeClientset.CoreV1().Secrets(secret.Namespace).Delete(secret.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Infof("could not delete secret %s/%s: %v", secret.Namespace, secret.Name, err)
+			continue
+		}
+		metrics.SecretOperation(metrics.SecretDeleted)
+	}
+}
+
+func sortSecrets(secrets []*corev1.Secret) {
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].Namespace != secrets[j].Namespace {
+			return secrets[i].Namespace < secrets[j].Namespace
+		}
+		return secrets[i].Name < secrets[j].Name
+	})
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+func newPruneFixture(t *testing.T, policy v1alpha1.PrunePolicy) (*fixture, *v1alpha1.Crypt, []*v1.Secret) {
+	f := newFixture(t)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name: "test-foo-secret",
+		Key:  "test/foo",
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+	crypt.UID = "test-crypt-uid"
+	crypt.Spec.PrunePolicy = policy
+
+	// secrets left behind by a renamed definition and by namespaces that no longer match, one of them created before
+	// secrets were labelled
+	stale := []*v1.Secret{
+		newSecret(nil, v1alpha1.SecretDefinition{Name: "test-old-secret"}, crypt, namespace.Name),
+		newSecret(nil, secretdef, crypt, "test-ns2"),
+		newLegacySecret(secretdef, crypt, "test-ns3"),
+	}
+	for _, secret := range stale {
+		f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+	}
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	obj, _ := f.store.Get(context.Background(), secretdef.Key)
+	f.expectCreateSecretAction(newSecret(obj.GetData(), secretdef, crypt, namespace.Name))
+
+	return f, crypt, stale
+}
+
+func TestStaleSecretsPruned(t *testing.T) {
+	f, crypt, stale := newPruneFixture(t, "")
+
+	for _, secret := range stale {
+		f.expectDeleteSecretAction(secret)
+	}
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(crypt.Spec.Secrets[0], "test-ns1", nil)})
+
+	f.run(getKey(crypt, t))
+}
+
+func TestStaleSecretsRetained(t *testing.T) {
+	f, crypt, _ := newPruneFixture(t, v1alpha1.PrunePolicyRetain)
+
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(crypt.Spec.Secrets[0], "test-ns1", nil)})
+
+	f.run(getKey(crypt, t))
+}
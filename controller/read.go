@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
+
+// keyCache holds the keys read during a single sync, so that a key is read once whatever the number of namespaces
+// its secret goes to. failed reads are kept as well, rather than hitting a failing store once per namespace.
+type keyCache map[storeKey]*storeRead
+
+// storeRead is a read of a key that is in flight or done. it is shared by every worker asking for the same key in
+// the meantime.
+type storeRead struct {
+	done chan struct{}
+	obj  store.Object
+	err  error
+}
+
+// readKey reads a key from a store, once per sync.
+func (c *Controller) readKey(ctx context.Context, reads keyCache, st store.Store, key string) (store.Object, error) {
+	id := storeKey{store: st, key: key}
+	if r, ok := reads[id]; ok {
+		return r.obj, r.err
+	}
+
+	r := c.sharedRead(ctx, id)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	reads[id] = r
+	return r.obj, r.err
+}
+
+// sharedRead starts reading a key, unless another worker is already reading it.
+func (c *Controller) sharedRead(ctx context.Context, id storeKey) *storeRead {
+	c.readsMu.Lock()
+	defer c.readsMu.Unlock()
+
+	if r, ok := c.reads[id]; ok {
+		return r
+	}
+
+	r := &storeRead{done: make(chan struct{})}
+	c.reads[id] = r
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, c.storeTimeout)
+		defer cancel()
+
+		start := time.Now()
+		r.obj, r.err = id.store.Get(ctx, id.key)
+		metrics.ObserveStoreGet(id.store, time.Since(start), r.err)
+
+		c.readsMu.Lock()
+		delete(c.reads, id)
+		c.readsMu.Unlock()
+		close(r.done)
+	}()
+
+	return r
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
+
+// countingStore counts the reads of its keys, and holds them until release is closed when it is set.
+type countingStore struct {
+	store.Store
+	gets    int32
+	release chan struct{}
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) (store.Object, error) {
+	atomic.AddInt32(&s.gets, 1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.Store.Get(ctx, key)
+}
+
+func TestKeyReadOncePerSync(t *testing.T) {
+	f := newFixture(t)
+
+	cs := &countingStore{Store: f.store}
+	f.stores.Register(defaultTestStore, cs)
+
+	namespaces := []string{"test-ns1", "test-ns2", "test-ns3"}
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: namespaces,
+		secrets: []v1alpha1.SecretDefinition{
+			{Name: "test-foo-secret", Key: "test/foo"},
+			{Name: "test-foo-copy", Key: "test/foo"},
+		},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	for _, ns := range namespaces {
+		f.namespaceLister = append(f.namespaceLister, newNamespace(ns))
+	}
+	f.initControllerLists()
+
+	if err := f.controller.syncHandler(context.Background(), getKey(crypt, t)); err != nil {
+		t.Fatalf("error syncing crypt: %v", err)
+	}
+	if cs.gets != 1 {
+		t.Errorf("expected test/foo to be read once, got %d reads", cs.gets)
+	}
+}
+
+func TestConcurrentReadsShared(t *testing.T) {
+	f := newFixture(t)
+
+	cs := &countingStore{Store: f.store, release: make(chan struct{})}
+	id := storeKey{store: cs, key: "test/foo"}
+
+	// a second worker asks for the key while the first read is still in flight
+	first := f.controller.sharedRead(context.Background(), id)
+	second := f.controller.sharedRead(context.Background(), id)
+	if first != second {
+		t.Fatal("expected the read in flight to be shared")
+	}
+
+	close(cs.release)
+	<-first.done
+
+	if first.err != nil {
+		t.Errorf("unexpected error: %v", first.err)
+	}
+	if gets := atomic.LoadInt32(&cs.gets); gets != 1 {
+		t.Errorf("expected test/foo to be read once, got %d reads", gets)
+	}
+	if _, ok := f.controller.reads[id]; ok {
+		t.Error("expected the read to be done")
+	}
+}
+
+func TestReadTimesOut(t *testing.T) {
+	f := newFixture(t)
+	f.controller.storeTimeout = time.Nanosecond
+
+	// the deadline of the read has passed by the time the store is asked for the key
+	if _, err := f.controller.readKey(context.Background(), make(keyCache), f.store, "test/foo"); err != context.DeadlineExceeded {
+		t.Errorf("expected the read to time out, got %v", err)
+	}
+}
@@ -0,0 +1,439 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"github.com/bluehoodie/crypt-controller/pkg/store/factory"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	log "k8s.io/klog"
+)
+
+const (
+	secretStoreKind        = "SecretStore"
+	clusterSecretStoreKind = "ClusterSecretStore"
+
+	// FailedStoreSync is used as part of the Event 'reason' when a store described by a SecretStore or
+	// ClusterSecretStore could not be built
+	FailedStoreSync = "StoreFailed"
+
+	// SuccessStoreSynced is used as part of the Event 'reason' when a store described by a SecretStore or
+	// ClusterSecretStore is ready to be read from
+	SuccessStoreSynced = "StoreReady"
+
+	// MessageStoreSynced is the message used for an Event fired when a store is ready to be read from
+	MessageStoreSynced = "Store connection configured successfully"
+
+	// StoreUnreachable is used as part of the Event 'reason' when a store described by a SecretStore or
+	// ClusterSecretStore could not be pinged
+	StoreUnreachable = "StoreUnreachable"
+
+	// StoreReachable is used as part of the Event 'reason' when a store described by a SecretStore or
+	// ClusterSecretStore can be pinged again
+	StoreReachable = "StoreReachable"
+)
+
+// secretStoreKey is the key a SecretStore is queued and registered under. static store names are DNS subdomains
+// and cannot collide with it.
+func secretStoreKey(namespace, name string) string {
+	return secretStoreKind + "/" + namespace + "/" + name
+}
+
+func clusterSecretStoreKey(name string) string {
+	return clusterSecretStoreKind + "/" + name
+}
+
+// isResourceStore tells whether a registered store was built from a SecretStore or ClusterSecretStore, rather than
+// configured when the controller started.
+func isResourceStore(name string) bool {
+	return strings.HasPrefix(name, secretStoreKind+"/") || strings.HasPrefix(name, clusterSecretStoreKind+"/")
+}
+
+// storeResource returns the SecretStore or ClusterSecretStore a store was built from, or nil when it is gone.
+func (c *Controller) storeResource(key string) runtime.Object {
+	parts := strings.Split(key, "/")
+	switch {
+	case len(parts) == 3 && parts[0] == secretStoreKind:
+		if s, err := c.secretStoreLister.SecretStores(parts[1]).Get(parts[2]); err == nil {
+			return s
+		}
+	case len(parts) == 2 && parts[0] == clusterSecretStoreKind:
+		if s, err := c.clusterSecretStoreLister.Get(parts[1]); err == nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// storeFor returns the store a secret definition reads from. a named store is looked up first among the
+// SecretStores of the Crypt's namespace, then among the ClusterSecretStores and finally among the stores
+// configured when the controller started.
+func (c *Controller) storeFor(sec v1alpha1.SecretDefinition, crypt *v1alpha1.Crypt) (store.Store, error) {
+	name := sec.GetStore()
+	if name != "" {
+		for _, key := range []string{secretStoreKey(crypt.Namespace, name), clusterSecretStoreKey(name)} {
+			if st, err := c.stores.Get(key); err == nil {
+				return st, nil
+			}
+		}
+	}
+
+	return c.stores.Get(name)
+}
+
+func (c *Controller) enqueueSecretStore(obj interface{}) {
+	switch s := obj.(type) {
+	case *v1alpha1.SecretStore:
+		c.storeQueue.Add(secretStoreKey(s.Namespace, s.Name))
+	case *v1alpha1.ClusterSecretStore:
+		c.storeQueue.Add(clusterSecretStoreKey(s.Name))
+	case cache.DeletedFinalStateUnknown:
+		c.enqueueSecretStore(s.Obj)
+	}
+}
+
+func (c *Controller) handleSecretStoreUpdate(old, new interface{}) {
+	// periodic resyncs would otherwise log in to the store again every time
+	if old.(metav1.Object).GetResourceVersion() == new.(metav1.Object).GetResourceVersion() {
+		return
+	}
+	c.enqueueSecretStore(new)
+}
+
+// enqueueSecretStoresForSecret queues the stores whose credentials are read from the given Secret.
+func (c *Controller) enqueueSecretStoresForSecret(secret *corev1.Secret) {
+	secretStores, err := c.secretStoreLister.SecretStores(secret.Namespace).List(labels.Everything())
+	if err == nil {
+		for _, s := range secretStores {
+			if referencesSecret(&s.Spec, s.Namespace, secret) {
+				c.enqueueSecretStore(s)
+			}
+		}
+	}
+
+	clusterSecretStores, err := c.clusterSecretStoreLister.List(labels.Everything())
+	if err == nil {
+		for _, s := range clusterSecretStores {
+			if referencesSecret(&s.Spec, "", secret) {
+				c.enqueueSecretStore(s)
+			}
+		}
+	}
+}
+
+func (c *Controller) runStoreWorker() {
+	for c.processNextStoreWorkItem() {
+	}
+}
+
+func (c *Controller) processNextStoreWorkItem() bool {
+	obj, shutdown := c.storeQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.storeQueue.Done(obj)
+
+	key, ok := obj.(string)
+	if !ok {
+		c.storeQueue.Forget(obj)
+		utilruntime.HandleError(fmt.Errorf("expected string in store queue but got %#v", obj))
+		return true
+	}
+
+	if err := c.syncStore(key); err != nil {
+		c.storeQueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing store '%s': %s, requeuing", key, err.Error()))
+		return true
+	}
+
+	c.storeQueue.Forget(obj)
+	return true
+}
+
+// syncStore builds the store described by a SecretStore or ClusterSecretStore and registers it, replacing any
+// store previously built for it. stores whose resource is gone, or whose configuration is not usable, are removed
+// so that Crypts do not keep reading from a connection that no longer matches its description.
+func (c *Controller) syncStore(key string) error {
+	parts := strings.Split(key, "/")
+
+	var obj runtime.Object
+	var spec *v1alpha1.SecretStoreSpec
+	var namespace, name string
+	var err error
+
+	switch {
+	case len(parts) == 3 && parts[0] == secretStoreKind:
+		namespace, name = parts[1], parts[2]
+		var s *v1alpha1.SecretStore
+		if s, err = c.secretStoreLister.SecretStores(namespace).Get(name); err == nil {
+			obj, spec = s, &s.Spec
+		}
+	case len(parts) == 2 && parts[0] == clusterSecretStoreKind:
+		name = parts[1]
+		var s *v1alpha1.ClusterSecretStore
+		if s, err = c.clusterSecretStoreLister.Get(name); err == nil {
+			obj, spec = s, &s.Spec
+		}
+	default:
+		utilruntime.HandleError(fmt.Errorf("invalid store key: %s", key))
+		return nil
+	}
+
+	if errors.IsNotFound(err) {
+		log.Infof("store %s was deleted", key)
+		c.stores.Unregister(key)
+		c.enqueueCryptsForStore(namespace, name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config, err := c.storeConfig(name, namespace, spec)
+	if err == nil {
+		var st store.Store
+		if st, err = factory.NewStore(*config); err == nil {
+			c.stores.Register(key, st)
+		}
+	}
+
+	if err != nil {
+		c.stores.Unregister(key)
+		c.recorder.Eventf(obj, corev1.EventTypeWarning, FailedStoreSync, "Could not configure store: %v", err)
+		c.enqueueCryptsForStore(namespace, name)
+		return err
+	}
+
+	c.recorder.Event(obj, corev1.EventTypeNormal, SuccessStoreSynced, MessageStoreSynced)
+	c.enqueueCryptsForStore(namespace, name)
+	return nil
+}
+
+// enqueueCryptsForStore queues the Crypts with a secret reading from the named store. an empty namespace matches
+// Crypts in every namespace, as ClusterSecretStores can be used from all of them.
+func (c *Controller) enqueueCryptsForStore(namespace, name string) {
+	var crypts []*v1alpha1.Crypt
+	var err error
+
+	if namespace == "" {
+		crypts, err = c.cryptLister.List(labels.Everything())
+	} else {
+		crypts, err = c.cryptLister.Crypts(namespace).List(labels.Everything())
+	}
+	if err != nil {
+		return
+	}
+
+	for _, crypt := range crypts {
+		for _, sec := range crypt.Spec.Secrets {
+			if sec.GetStore() == name {
+				c.enqueueCrypt(crypt)
+				break
+			}
+		}
+	}
+}
+
+// storeConfig converts a store resource into the configuration understood by the store factory, reading the
+// credentials it references. SecretStores may only reference Secrets in their own namespace.
+func (c *Controller) storeConfig(name, namespace string, spec *v1alpha1.SecretStoreSpec) (*factory.StoreConfig, error) {
+	config := &factory.StoreConfig{Name: name}
+
+	switch {
+	case spec.Consul != nil && spec.Vault != nil:
+		return nil, fmt.Errorf("only one of consul or vault may be configured")
+	case spec.Consul != nil:
+		config.Type = factory.ConsulStoreType
+		consul, err := c.consulConfig(namespace, spec.Consul)
+		if err != nil {
+			return nil, err
+		}
+		config.Consul = consul
+	case spec.Vault != nil:
+		config.Type = factory.VaultStoreType
+		vault, err := c.vaultConfig(namespace, spec.Vault)
+		if err != nil {
+			return nil, err
+		}
+		config.Vault = vault
+	default:
+		return nil, fmt.Errorf("one of consul or vault must be configured")
+	}
+
+	return config, nil
+}
+
+func (c *Controller) consulConfig(namespace string, spec *v1alpha1.ConsulStoreSpec) (*factory.ConsulConfig, error) {
+	config := &factory.ConsulConfig{
+		Address:    spec.Address,
+		Scheme:     spec.Scheme,
+		Datacenter: spec.Datacenter,
+	}
+
+	if spec.Timeout != nil {
+		config.Timeout = *spec.Timeout
+	}
+
+	var err error
+	if config.TLS, err = c.tlsConfig(namespace, spec.TLS); err != nil {
+		return nil, err
+	}
+
+	if spec.TokenSecretRef != nil {
+		if config.Token, err = c.secretValue(namespace, spec.TokenSecretRef); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func (c *Controller) vaultConfig(namespace string, spec *v1alpha1.VaultStoreSpec) (*factory.VaultConfig, error) {
+	config := &factory.VaultConfig{
+		Address:   spec.Address,
+		Namespace: spec.Namespace,
+		KV: factory.VaultKVConfig{
+			Mount:   spec.Mount,
+			Version: spec.KVVersion,
+		},
+	}
+
+	if spec.Timeout != nil {
+		config.Timeout = *spec.Timeout
+	}
+
+	var err error
+	if config.TLS, err = c.tlsConfig(namespace, spec.TLS); err != nil {
+		return nil, err
+	}
+
+	auth := spec.Auth
+	switch {
+	case auth.Kubernetes != nil:
+		// the controller's own service account token is sent to the store's address, which a namespaced
+		// SecretStore is free to choose
+		if namespace != "" {
+			return nil, fmt.Errorf("vault kubernetes auth is only allowed on a ClusterSecretStore")
+		}
+		config.Auth = factory.VaultAuthConfig{
+			Method:    factory.VaultKubernetesAuth,
+			Role:      auth.Kubernetes.Role,
+			MountPath: auth.Kubernetes.MountPath,
+		}
+	case auth.AppRole != nil:
+		config.Auth = factory.VaultAuthConfig{
+			Method:    factory.VaultAppRoleAuth,
+			RoleID:    auth.AppRole.RoleID,
+			MountPath: auth.AppRole.MountPath,
+		}
+		if auth.AppRole.SecretIDSecretRef != nil {
+			if config.Auth.SecretID, err = c.secretValue(namespace, auth.AppRole.SecretIDSecretRef); err != nil {
+				return nil, err
+			}
+		}
+	case auth.TokenSecretRef != nil:
+		config.Auth = factory.VaultAuthConfig{Method: factory.VaultTokenAuth}
+		if config.Auth.Token, err = c.secretValue(namespace, auth.TokenSecretRef); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("one of vault auth tokenSecretRef, kubernetes or appRole must be configured")
+	}
+
+	return config, nil
+}
+
+func (c *Controller) tlsConfig(namespace string, spec *v1alpha1.StoreTLSSpec) (*factory.TLSConfig, error) {
+	if spec == nil {
+		return nil, nil
+	}
+
+	config := &factory.TLSConfig{
+		ServerName:         spec.ServerName,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}
+
+	var err error
+	if spec.CASecretRef != nil {
+		if config.CAData, err = c.secretValue(namespace, spec.CASecretRef); err != nil {
+			return nil, err
+		}
+	}
+	if spec.ClientCertSecretRef != nil {
+		if config.CertData, err = c.secretValue(namespace, spec.ClientCertSecretRef); err != nil {
+			return nil, err
+		}
+	}
+	if spec.ClientKeySecretRef != nil {
+		if config.KeyData, err = c.secretValue(namespace, spec.ClientKeySecretRef); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// secretValue reads a key of a Secret. namespace is the namespace of a SecretStore, or empty for a
+// ClusterSecretStore, in which case the selector must name the Secret's namespace.
+func (c *Controller) secretValue(namespace string, ref *v1alpha1.SecretKeySelector) (string, error) {
+	secretNamespace := refNamespace(namespace, ref)
+	if secretNamespace == "" {
+		return "", fmt.Errorf("namespace is required to reference secret %s from a ClusterSecretStore", ref.Name)
+	}
+
+	secret, err := c.secretLister.Secrets(secretNamespace).Get(ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("could not get secret %s/%s: %v", secretNamespace, ref.Name, err)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %s", secretNamespace, ref.Name, ref.Key)
+	}
+
+	return strings.TrimSpace(string(value)), nil
+}
+
+func refNamespace(namespace string, ref *v1alpha1.SecretKeySelector) string {
+	if namespace != "" {
+		return namespace
+	}
+	return ref.Namespace
+}
+
+// referencesSecret reports whether a store resource in the given namespace reads credentials from the Secret.
+func referencesSecret(spec *v1alpha1.SecretStoreSpec, namespace string, secret *corev1.Secret) bool {
+	var refs []*v1alpha1.SecretKeySelector
+
+	var tls *v1alpha1.StoreTLSSpec
+	if spec.Consul != nil {
+		refs = append(refs, spec.Consul.TokenSecretRef)
+		tls = spec.Consul.TLS
+	}
+	if spec.Vault != nil {
+		refs = append(refs, spec.Vault.Auth.TokenSecretRef)
+		if spec.Vault.Auth.AppRole != nil {
+			refs = append(refs, spec.Vault.Auth.AppRole.SecretIDSecretRef)
+		}
+		tls = spec.Vault.TLS
+	}
+	if tls != nil {
+		refs = append(refs, tls.CASecretRef, tls.ClientCertSecretRef, tls.ClientKeySecretRef)
+	}
+
+	for _, ref := range refs {
+		if ref != nil && ref.Name == secret.Name && refNamespace(namespace, ref) == secret.Namespace {
+			return true
+		}
+	}
+
+	return false
+}
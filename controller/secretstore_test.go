@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"github.com/bluehoodie/crypt-controller/pkg/store/factory"
+	"github.com/bluehoodie/crypt-controller/pkg/store/memory"
+)
+
+func TestSecretsCreatedFromSecretStore(t *testing.T) {
+	f := newFixture(t)
+
+	// a SecretStore in the Crypt's namespace takes precedence over a static store with the same name
+	secretStoreMap := map[string]store.Object{
+		"test/foo": store.Object(map[string][]byte{"foo": []byte("secretStoreFooSecret")}),
+	}
+	secretStore, _ := memory.New(secretStoreMap)
+	f.stores.Register(secretStoreKey("default", otherTestStore), secretStore)
+
+	secretdef := v1alpha1.SecretDefinition{
+		Name:  "test-foo-secret",
+		Key:   "test/foo",
+		Store: otherTestStore,
+	}
+
+	namespace := newNamespace("test-ns1")
+
+	crypt := newCrypt(&cryptOpts{
+		name:             "test-crypt",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{secretdef},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.cryptObjects = append(f.cryptObjects, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.kubeObjects = append(f.kubeObjects, namespace)
+
+	f.expectCreateSecretAction(newSecret(secretStoreMap["test/foo"].GetData(), secretdef, crypt, namespace.Name))
+	f.expectUpdateCryptStatusAction(crypt, []v1alpha1.SecretStatus{secretStatus(secretdef, namespace.Name, nil)})
+
+	f.run(getKey(crypt, t))
+}
+
+func TestStoreConfigReadsSecretRefs(t *testing.T) {
+	f := newFixture(t)
+
+	credentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "consul-credentials", Namespace: "team-a"},
+		Data:       map[string][]byte{"token": []byte("acl-token\n")},
+	}
+	f.k8sInformer.Core().V1().Secrets().Informer().GetIndexer().Add(credentials)
+
+	spec := &v1alpha1.SecretStoreSpec{
+		Consul: &v1alpha1.ConsulStoreSpec{
+			Address: "consul:8500",
+			TokenSecretRef: &v1alpha1.SecretKeySelector{
+				Name: "consul-credentials",
+				Key:  "token",
+			},
+		},
+	}
+
+	config, err := f.controller.storeConfig("consul", "team-a", spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Type != factory.ConsulStoreType || config.Consul.Token != "acl-token" {
+		t.Errorf("unexpected store config %+v, consul %+v", config, config.Consul)
+	}
+
+	// a ClusterSecretStore must say which namespace the Secret lives in
+	if _, err := f.controller.storeConfig("consul", "", spec); err == nil {
+		t.Error("expected error for secret reference without namespace")
+	}
+
+	if !referencesSecret(spec, "team-a", credentials) {
+		t.Error("expected store to reference the credentials secret")
+	}
+}
+
+func TestStoreConfigRefusesKubernetesAuthOnSecretStore(t *testing.T) {
+	f := newFixture(t)
+
+	spec := &v1alpha1.SecretStoreSpec{
+		Vault: &v1alpha1.VaultStoreSpec{
+			Address: "https://attacker.example.com:8200",
+			Auth: v1alpha1.VaultAuthSpec{
+				Kubernetes: &v1alpha1.VaultKubernetesAuthSpec{Role: "crypt-controller"},
+			},
+		},
+	}
+
+	// a namespaced SecretStore must not be able to send the controller's token to an address of its choosing
+	if _, err := f.controller.storeConfig("vault", "team-a", spec); err == nil {
+		t.Error("expected error for kubernetes auth on a SecretStore")
+	}
+
+	config, err := f.controller.storeConfig("vault", "", spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Vault.Auth.Method != factory.VaultKubernetesAuth {
+		t.Errorf("expected kubernetes auth, got %+v", config.Vault.Auth)
+	}
+}
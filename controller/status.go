@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FailedSync is used as the reason of the Ready condition when some secrets of a Crypt could not be synced
+	FailedSync = "SyncFailed"
+	// PermanentSyncFailure is used as the reason of the Ready condition when the secrets of a Crypt that could not be
+	// synced all failed for errors that retrying will not fix, such as a missing key
+	PermanentSyncFailure = "PermanentSyncFailure"
+	// InvalidSpec is used as the reason of the Ready condition when the spec of a Crypt is invalid
+	InvalidSpec = "InvalidSpec"
+	// SecretsClaimed is used as the reason of the Conflict condition when another Crypt takes precedence for some
+	// secrets of a Crypt
+	SecretsClaimed = "SecretsClaimed"
+	// NoConflict is used as the reason of the Conflict condition when no other Crypt defines the secrets of a Crypt
+	NoConflict = "NoConflict"
+
+	// StatusRefreshInterval is how often the lastSyncTime of a Crypt whose status did not otherwise change is written
+	StatusRefreshInterval = 5 * time.Minute
+)
+
+func secretStatus(def v1alpha1.SecretDefinition, namespace string, err error) v1alpha1.SecretStatus {
+	result := v1alpha1.SecretStatus{
+		Namespace: namespace,
+		Name:      def.GetName(),
+		Key:       def.GetKey(),
+		Synced:    err == nil,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Reason = failureReason(err)
+	}
+	return result
+}
+
+// claimedStatus records a secret that is not written, because the winner Crypt defines it in the same namespace.
+func claimedStatus(def v1alpha1.SecretDefinition, namespace string, winner *v1alpha1.Crypt) v1alpha1.SecretStatus {
+	result := secretStatus(def, namespace, fmt.Errorf("secret is defined by crypt %s/%s, which takes precedence", winner.Namespace, winner.Name))
+	result.Reason = SecretsClaimed
+	result.ClaimedBy = winner.Namespace + "/" + winner.Name
+	return result
+}
+
+// prefixStatus records a prefix whose keys could not be listed, and so were not synced anywhere.
+func prefixStatus(sec v1alpha1.SecretDefinition, err error) v1alpha1.SecretStatus {
+	return v1alpha1.SecretStatus{
+		Key:    sec.GetPrefix(),
+		Error:  err.Error(),
+		Reason: failureReason(err),
+	}
+}
+
+// cryptStatus computes the status of a Crypt from the outcome of syncing each of its secrets.
+func cryptStatus(crypt *v1alpha1.Crypt, results []v1alpha1.SecretStatus, now metav1.Time) v1alpha1.CryptStatus {
+	status := *crypt.Status.DeepCopy()
+	status.ObservedGeneration = crypt.Generation
+	status.LastSyncTime = &now
+	status.Secrets = results
+
+	failed, transient, claimed := 0, 0, 0
+	var permanent []string
+	seen := make(map[string]struct{})
+	names := make(map[string]struct{})
+	namespaces := make(map[string]struct{})
+	for _, result := range results {
+		// secrets left to another Crypt are reported by the Conflict condition rather than as failures
+		if result.ClaimedBy != "" {
+			claimed++
+		} else if !result.Synced {
+			failed++
+			if isTransientReason(result.Reason) {
+				transient++
+			} else if _, ok := seen[result.Reason]; !ok {
+				seen[result.Reason] = struct{}{}
+				permanent = append(permanent, result.Reason)
+			}
+		}
+		// prefixes that could not be listed have no secret or namespace
+		if result.Namespace != "" {
+			names[result.Name] = struct{}{}
+			namespaces[result.Namespace] = struct{}{}
+		}
+	}
+	status.SecretCount = len(names)
+	status.NamespaceCount = len(namespaces)
+
+	condition := v1alpha1.CryptCondition{
+		Type:               v1alpha1.CryptReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: now,
+		Reason:             SuccessSynced,
+		Message:            fmt.Sprintf("%d secrets synced", len(results)-claimed),
+	}
+	if failed > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = FailedSync
+		condition.Message = fmt.Sprintf("%d of %d secrets could not be synced", failed, len(results)-claimed)
+	}
+	if len(permanent) > 0 {
+		// tell a mistyped key or a missing permission apart from an outage, which is retried
+		if transient == 0 {
+			condition.Reason = PermanentSyncFailure
+		}
+		condition.Message += fmt.Sprintf("; %s failures are not retried", strings.Join(permanent, ", "))
+	}
+	status.SetCondition(condition)
+
+	conflict := v1alpha1.CryptCondition{
+		Type:               v1alpha1.CryptConflict,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: now,
+		Reason:             NoConflict,
+	}
+	if claimed > 0 {
+		conflict.Status = corev1.ConditionTrue
+		conflict.Reason = SecretsClaimed
+		conflict.Message = fmt.Sprintf("%d of %d secrets are defined by crypts that take precedence", claimed, len(results))
+	}
+	status.SetCondition(conflict)
+
+	return status
+}
+
+// invalidStatus marks a Crypt that could not be synced at all because of its spec. the secrets of the previous sync
+// are left as they are, and so is their status.
+func invalidStatus(crypt *v1alpha1.Crypt, reason string, err error, now metav1.Time) v1alpha1.CryptStatus {
+	status := *crypt.Status.DeepCopy()
+	status.ObservedGeneration = crypt.Generation
+	status.SetCondition(v1alpha1.CryptCondition{
+		Type:               v1alpha1.CryptReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	return status
+}
+
+func (c *Controller) updateStatus(crypt *v1alpha1.Crypt, results []v1alpha1.SecretStatus) error {
+	return c.writeStatus(crypt, cryptStatus(crypt, results, c.now()))
+}
+
+func (c *Controller) updateStatusInvalid(crypt *v1alpha1.Crypt, reason string, err error) error {
+	return c.writeStatus(crypt, invalidStatus(crypt, reason, err, c.now()))
+}
+
+// statusChanged reports whether status is worth writing over the current one. a status that only moves the
+// lastSyncTime forward is left out until the current lastSyncTime is StatusRefreshInterval old, so that periodic resyncs
+// do not update every Crypt each time.
+func statusChanged(current, status v1alpha1.CryptStatus) bool {
+	if current.LastSyncTime != nil && status.LastSyncTime != nil {
+		if status.LastSyncTime.Sub(current.LastSyncTime.Time) >= StatusRefreshInterval {
+			return true
+		}
+		current.LastSyncTime = status.LastSyncTime
+	}
+	return !apiequality.Semantic.DeepEqual(current, status)
+}
+
+func (c *Controller) writeStatus(crypt *v1alpha1.Crypt, status v1alpha1.CryptStatus) error {
+	if !statusChanged(crypt.Status, status) {
+		return nil
+	}
+
+	cryptCopy := crypt.DeepCopy()
+	cryptCopy.Status = status
+
+	_, err := c.cryptClientset.CoreV1alpha1().Crypts(crypt.Namespace).UpdateStatus(cryptCopy)
+	if errors.IsNotFound(err) {
+		// the crypt was deleted while it was synced
+		return nil
+	}
+	return err
+}
+
+func (c *Controller) handleCryptUpdate(old, new interface{}) {
+	oldCrypt, ok := old.(*v1alpha1.Crypt)
+	if !ok {
+		return
+	}
+	newCrypt, ok := new.(*v1alpha1.Crypt)
+	if !ok {
+		return
+	}
+
+	// the status written at the end of each sync, or the finalizer added before it, would otherwise trigger another
+	// one. periodic resyncs keep the same resource version and still go through.
+	if oldCrypt.ResourceVersion != newCrypt.ResourceVersion && oldCrypt.Generation == newCrypt.Generation &&
+		oldCrypt.DeletionTimestamp.Equal(newCrypt.DeletionTimestamp) {
+		return
+	}
+
+	c.enqueueCrypt(new)
+	// the crypts it took secrets from may write them now
+	if oldCrypt.Generation != newCrypt.Generation {
+		c.enqueueClaimants(newCrypt)
+	}
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	log "k8s.io/klog"
+)
+
+// storeKey identifies a key of a store. stores rebuilt from a SecretStore are new values, so their keys are watched
+// and read again, and the watches on the store they replaced are pruned.
+type storeKey struct {
+	store store.Store
+	key   string
+}
+
+// watchKey starts watching a key of a store that supports it, unless the key is already watched. the watch lasts
+// until it is pruned or the context is done.
+func (c *Controller) watchKey(ctx context.Context, st store.Store, key string) {
+	watcher, ok := st.(store.Watcher)
+	if !ok {
+		return
+	}
+
+	id := storeKey{store: st, key: key}
+
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	if _, ok := c.watches[id]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.watches[id] = cancel
+
+	changes := watcher.Watch(ctx, key)
+	go func() {
+		for range changes {
+			log.V(4).Infof("key %s changed in store", key)
+			c.enqueueCryptsForKey(st, key)
+		}
+	}()
+}
+
+// recordKeys keeps the keys a Crypt read during a sync. the keys of its previous sync are kept as well when some of
+// its prefixes could not be listed, as the keys under them are still read.
+func (c *Controller) recordKeys(cryptKey string, keys map[storeKey]struct{}, partial bool) {
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	if partial {
+		for id := range c.cryptKeys[cryptKey] {
+			keys[id] = struct{}{}
+		}
+	}
+	c.cryptKeys[cryptKey] = keys
+}
+
+// pruneWatches stops watching keys that are no longer read by any Crypt, and forgets the keys of deleted Crypts.
+func (c *Controller) pruneWatches() {
+	crypts, err := c.cryptLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	existing := make(map[string]struct{})
+	wanted := make(map[storeKey]struct{})
+	for _, crypt := range crypts {
+		key, err := cache.MetaNamespaceKeyFunc(crypt)
+		if err != nil {
+			continue
+		}
+		existing[key] = struct{}{}
+		for id := range c.cryptKeys[key] {
+			wanted[id] = struct{}{}
+		}
+	}
+
+	for key := range c.cryptKeys {
+		if _, ok := existing[key]; !ok {
+			delete(c.cryptKeys, key)
+		}
+	}
+
+	for id, cancel := range c.watches {
+		if _, ok := wanted[id]; !ok {
+			cancel()
+			delete(c.watches, id)
+		}
+	}
+}
+
+func (c *Controller) stopWatches() {
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	for id, cancel := range c.watches {
+		cancel()
+		delete(c.watches, id)
+	}
+}
+
+// enqueueCryptsForKey queues the Crypts that read the key from the given store during their last sync.
+func (c *Controller) enqueueCryptsForKey(st store.Store, key string) {
+	id := storeKey{store: st, key: key}
+
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	for cryptKey, keys := range c.cryptKeys {
+		if _, ok := keys[id]; ok {
+			c.queue.AddRateLimited(cryptKey)
+		}
+	}
+}
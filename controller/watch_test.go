@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
+
+// watchStore reports a change to a key whenever a value is sent on its changes channel.
+type watchStore struct {
+	store.Store
+	key     string
+	changes chan struct{}
+}
+
+func (s *watchStore) Watch(ctx context.Context, key string) <-chan struct{} {
+	if key != s.key {
+		return make(chan struct{})
+	}
+	return s.changes
+}
+
+func TestCryptsEnqueuedOnKeyChange(t *testing.T) {
+	f := newFixture(t)
+
+	ws := &watchStore{Store: f.store, key: "test/foo", changes: make(chan struct{})}
+	f.stores.Register(defaultTestStore, ws)
+
+	namespace := newNamespace("test-ns1")
+	watched := newCrypt(&cryptOpts{
+		name:             "watched",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{{Name: "foo", Key: "test/foo"}},
+	})
+	other := newCrypt(&cryptOpts{
+		name:             "other",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{{Name: "bar", Key: "test/bar"}},
+	})
+
+	f.cryptLister = append(f.cryptLister, watched, other)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.initControllerLists()
+
+	if err := f.controller.syncHandler(context.Background(), getKey(watched, t)); err != nil {
+		t.Fatalf("error syncing crypt: %v", err)
+	}
+	if len(f.controller.watches) != 1 {
+		t.Fatalf("expected 1 watch, got %d", len(f.controller.watches))
+	}
+
+	ws.changes <- struct{}{}
+
+	got := make(chan interface{})
+	go func() {
+		key, _ := f.controller.queue.Get()
+		got <- key
+	}()
+
+	select {
+	case key := <-got:
+		if key != getKey(watched, t) {
+			t.Errorf("expected %s to be queued, got %v", getKey(watched, t), key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crypt was not queued after the key changed")
+	}
+	if f.controller.queue.Len() != 0 {
+		t.Errorf("expected only the crypt reading the key to be queued, %d more queued", f.controller.queue.Len())
+	}
+
+	// the watch is stopped once no crypt reads the key anymore
+	f.cryptInformer.Core().V1alpha1().Crypts().Informer().GetIndexer().Delete(watched)
+	f.controller.pruneWatches()
+	if len(f.controller.watches) != 0 {
+		t.Errorf("expected watch to be pruned, %d left", len(f.controller.watches))
+	}
+}
+
+// listingWatchStore is a watchStore that can list the keys of the store it wraps.
+type listingWatchStore struct {
+	*watchStore
+}
+
+func (s listingWatchStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.Store.(store.Lister).List(ctx, prefix)
+}
+
+func TestCryptsEnqueuedOnPrefixKeyChange(t *testing.T) {
+	f := newFixture(t)
+
+	ws := &watchStore{Store: f.store, key: "test/foo", changes: make(chan struct{})}
+	f.stores.Register(defaultTestStore, listingWatchStore{ws})
+
+	namespace := newNamespace("test-ns1")
+	crypt := newCrypt(&cryptOpts{
+		name:             "prefixed",
+		namespace:        "default",
+		targetNamespaces: []string{namespace.Name},
+		secrets:          []v1alpha1.SecretDefinition{{Prefix: "test/"}},
+	})
+
+	f.cryptLister = append(f.cryptLister, crypt)
+	f.namespaceLister = append(f.namespaceLister, namespace)
+	f.initControllerLists()
+
+	if err := f.controller.syncHandler(context.Background(), getKey(crypt, t)); err != nil {
+		t.Fatalf("error syncing crypt: %v", err)
+	}
+
+	// the watches on the keys found under the prefix are kept
+	f.controller.pruneWatches()
+	if len(f.controller.watches) != 2 {
+		t.Fatalf("expected 2 watches, got %d", len(f.controller.watches))
+	}
+
+	ws.changes <- struct{}{}
+
+	got := make(chan interface{})
+	go func() {
+		key, _ := f.controller.queue.Get()
+		got <- key
+	}()
+
+	select {
+	case key := <-got:
+		if key != getKey(crypt, t) {
+			t.Errorf("expected %s to be queued, got %v", getKey(crypt, t), key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crypt was not queued after a key under its prefix changed")
+	}
+}
@@ -1,17 +1,22 @@
+//go:build !windows
 // +build !windows
 
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	log "k8s.io/klog"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -19,22 +24,62 @@ import (
 	"github.com/bluehoodie/crypt-controller/controller"
 	clientset "github.com/bluehoodie/crypt-controller/pkg/client/clientset/versioned"
 	informers "github.com/bluehoodie/crypt-controller/pkg/client/informers/externalversions"
+	"github.com/bluehoodie/crypt-controller/pkg/health"
+	"github.com/bluehoodie/crypt-controller/pkg/metrics"
 	"github.com/bluehoodie/crypt-controller/pkg/store/factory"
+	"github.com/bluehoodie/crypt-controller/pkg/webhook"
 )
 
 var (
-	masterURL   string
-	kubeConfig  string
-	storeType   string
-	storeConfig string
+	masterURL    string
+	kubeConfig   string
+	storeType    string
+	storeConfig  string
+	storeTimeout time.Duration
+
+	metricsAddr string
+	healthAddr  string
+
+	leaderElect             bool
+	leaderElectionNamespace string
+	leaderElectionID        string
+	leaderElectionIdentity  string
+	leaseDuration           time.Duration
+	renewDeadline           time.Duration
+	retryPeriod             time.Duration
+
+	webhookAddr      string
+	webhookNamespace string
+	webhookService   string
+	webhookSecret    string
+	webhookConfig    string
 )
 
 func init() {
 	flag.StringVar(&kubeConfig, "kubeConfig", os.Getenv("KUBECONFIGPATH"), "Path to a kubeConfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeConfig. Only required if out-of-cluster.")
 
-	flag.StringVar(&storeType, "storeType", os.Getenv("STORE_TYPE"), "The type of store to use a secret source.")
+	flag.StringVar(&storeType, "storeType", os.Getenv("STORE_TYPE"), "The type of store to use a secret source. Not required when the store config lists named stores.")
 	flag.StringVar(&storeConfig, "storeConfig", os.Getenv("STORE_CONFIG"), "Path to a store config.")
+	flag.DurationVar(&storeTimeout, "storeTimeout", controller.DefaultStoreTimeout, "How long a single read from a store may take.")
+
+	flag.StringVar(&metricsAddr, "metricsAddr", ":8080", "The address the metrics are served on. Metrics are not served when empty.")
+	flag.StringVar(&healthAddr, "healthAddr", ":8081", "The address the /healthz and /readyz endpoints are served on. They are not served when empty.")
+
+	hostname, _ := os.Hostname()
+	flag.BoolVar(&leaderElect, "leaderElect", false, "Elect a leader among the replicas of the controller, so that only the leader syncs crypts.")
+	flag.StringVar(&leaderElectionNamespace, "leaderElectionNamespace", os.Getenv("POD_NAMESPACE"), "The namespace of the Lease the replicas compete for.")
+	flag.StringVar(&leaderElectionID, "leaderElectionID", "crypt-controller", "The name of the Lease the replicas compete for.")
+	flag.StringVar(&leaderElectionIdentity, "leaderElectionIdentity", hostname, "The name this replica holds the Lease under. Defaults to the hostname, which is the name of the pod.")
+	flag.DurationVar(&leaseDuration, "leaseDuration", 15*time.Second, "How long standby replicas wait after the last renewal of the Lease before they take it over.")
+	flag.DurationVar(&renewDeadline, "renewDeadline", 10*time.Second, "How long the leader keeps trying to renew the Lease before it gives up leading.")
+	flag.DurationVar(&retryPeriod, "retryPeriod", 2*time.Second, "How often replicas try to acquire or renew the Lease.")
+
+	flag.StringVar(&webhookAddr, "webhookAddr", "", "The address the validating webhook listens on, such as :8443. The webhook is disabled when empty.")
+	flag.StringVar(&webhookNamespace, "webhookNamespace", os.Getenv("POD_NAMESPACE"), "The namespace of the webhook service and of the secret holding its certificate.")
+	flag.StringVar(&webhookService, "webhookService", "crypt-controller-webhook", "The name of the service the API server reaches the webhook through.")
+	flag.StringVar(&webhookSecret, "webhookSecret", "crypt-controller-webhook-tls", "The name of the secret holding the certificate of the webhook.")
+	flag.StringVar(&webhookConfig, "webhookConfig", "crypt-controller", "The name of the ValidatingWebhookConfiguration the certificate authority is injected into.")
 }
 
 func main() {
@@ -42,7 +87,7 @@ func main() {
 
 	stop := make(chan struct{})
 	go func() {
-		signalStream := make(chan os.Signal)
+		signalStream := make(chan os.Signal, 1)
 		signal.Notify(signalStream, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT)
 
 		sig := <-signalStream
@@ -54,13 +99,9 @@ func main() {
 		os.Exit(1)
 	}()
 
-	if storeType == "" {
-		log.Fatal("STORE_TYPE not defined")
-	}
-
-	store, err := factory.NewStoreFactory(storeConfig).Make(storeType)
+	stores, err := factory.NewStoreFactory(storeConfig).MakeRegistry(storeType)
 	if err != nil {
-		log.Fatalf("Could not initialize store: %v", err)
+		log.Fatalf("Could not initialize stores: %v", err)
 	}
 
 	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeConfig)
@@ -85,14 +126,152 @@ func main() {
 		kubeInformerFactory.Core().V1().Namespaces(),
 		kubeInformerFactory.Core().V1().Secrets(),
 		cryptInformerFactory.Core().V1alpha1().Crypts(),
-		store,
+		cryptInformerFactory.Core().V1alpha1().SecretStores(),
+		cryptInformerFactory.Core().V1alpha1().ClusterSecretStores(),
+		stores,
+		controller.WithStoreTimeout(storeTimeout),
 	)
 
+	if metricsAddr != "" {
+		go func() {
+			log.Infof("serving metrics on %s", metricsAddr)
+			if err := metrics.Serve(metricsAddr, stop); err != nil {
+				log.Fatalf("Error serving metrics: %v", err)
+			}
+		}()
+	}
+
+	// the leader is not live once it can no longer renew the lease
+	var watchDog *leaderelection.HealthzAdaptor
+	if leaderElect {
+		watchDog = leaderelection.NewLeaderHealthzAdaptor(20 * time.Second)
+	}
+
+	if healthAddr != "" {
+		// every replica probes the stores, so that readiness does not depend on leading
+		c.StartProbes(stop)
+		go runHealth(c, watchDog, stop)
+	}
+
+	if webhookAddr != "" {
+		go runWebhook(kubeClient, stop)
+	}
+
 	kubeInformerFactory.Start(stop)
 	cryptInformerFactory.Start(stop)
 
-	err = c.Run(3, stop)
+	run := func(stop <-chan struct{}) {
+		if err := c.Run(3, stop); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// standby replicas keep their informers in sync, and only start their workers once they lead
+	if leaderElect {
+		runLeaderElection(kubeClient, watchDog, run, stop)
+		return
+	}
+	run(stop)
+}
+
+func runLeaderElection(kubeClient kubernetes.Interface, watchDog *leaderelection.HealthzAdaptor, run func(<-chan struct{}), stop <-chan struct{}) {
+	if leaderElectionNamespace == "" {
+		log.Fatal("-leaderElectionNamespace or POD_NAMESPACE must be set to elect a leader")
+	}
+
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, leaderElectionNamespace, leaderElectionID,
+		kubeClient.CoreV1(), kubeClient.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: leaderElectionIdentity})
+	if err != nil {
+		log.Fatalf("Error creating leader election lock: %v", err)
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		// a replica shutting down lets the next one take over right away
+		ReleaseOnCancel: true,
+		WatchDog:        watchDog,
+		Name:            leaderElectionID,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infof("%s is leading, starting workers", leaderElectionIdentity)
+				run(ctx.Done())
+			},
+			OnStoppedLeading: func() {
+				select {
+				case <-stop:
+					log.Infof("%s released the lease", leaderElectionIdentity)
+				default:
+					// the workers may still be writing secrets, so the replica restarts as a standby instead
+					log.Fatalf("%s lost the lease", leaderElectionIdentity)
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if identity != leaderElectionIdentity {
+					log.Infof("%s is leading", identity)
+				}
+			},
+		},
+	})
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Invalid leader election settings: %v", err)
+	}
+	watchDog.SetLeaderElection(elector)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	elector.Run(ctx)
+}
+
+func runHealth(c *controller.Controller, watchDog *leaderelection.HealthzAdaptor, stop <-chan struct{}) {
+	workers := health.Check{Name: "workers", Check: c.WorkersAlive}
+	live := []health.Check{workers}
+	ready := []health.Check{
+		{Name: "informers", Check: c.InformersSynced},
+		workers,
+		{Name: "stores", Check: c.StoresReachable},
+	}
+	if watchDog != nil {
+		live = append(live, health.Check{Name: "leaderElection", Check: func() error { return watchDog.Check(nil) }})
+	}
+
+	log.Infof("serving health checks on %s", healthAddr)
+	if err := health.Serve(healthAddr, live, ready, stop); err != nil {
+		log.Fatalf("Error serving health checks: %v", err)
+	}
+}
+
+func runWebhook(kubeClient kubernetes.Interface, stop <-chan struct{}) {
+	certificate := &webhook.Certificate{}
+	ensureCertificate := func() error {
+		certPEM, keyPEM, err := webhook.EnsureCertificate(kubeClient, webhookNamespace, webhookSecret, webhookService)
+		if err != nil {
+			return err
+		}
+		return certificate.Set(certPEM, keyPEM)
+	}
+	if err := ensureCertificate(); err != nil {
+		log.Fatalf("Could not get webhook certificate: %v", err)
+	}
+
+	// the certificate is renewed before it expires, or picked up once another replica renewed it. the configuration
+	// loses its CA bundle whenever it is applied again, so it is checked as well.
+	go wait.Until(func() {
+		if err := ensureCertificate(); err != nil {
+			log.Errorf("could not renew webhook certificate: %v", err)
+		}
+		if err := webhook.InjectCABundle(kubeClient, webhookConfig, certificate.PEM()); err != nil {
+			log.Errorf("could not inject CA bundle into %s: %v", webhookConfig, err)
+		}
+	}, time.Minute, stop)
+
+	log.Infof("serving webhook on %s", webhookAddr)
+	if err := webhook.Serve(webhookAddr, certificate, stop); err != nil {
+		log.Fatalf("Error serving webhook: %v", err)
 	}
 }
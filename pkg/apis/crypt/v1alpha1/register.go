@@ -44,6 +44,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Crypt{},
 		&CryptList{},
+		&SecretStore{},
+		&SecretStoreList{},
+		&ClusterSecretStore{},
+		&ClusterSecretStoreList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -14,33 +14,178 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"path"
+	"regexp"
+
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Secrets",type=integer,JSONPath=".status.secretCount"
+// +kubebuilder:printcolumn:name="Namespaces",type=integer,JSONPath=".status.namespaceCount"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 // Crypt is a specification for a Crypt resource
 type Crypt struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   CryptSpec   `json:"spec"`
+	Spec CryptSpec `json:"spec"`
+	// +optional
 	Status CryptStatus `json:"status"`
 }
 
 type CryptSpec struct {
-	Secrets    []SecretDefinition `json:"secrets"`
-	Namespaces []string           `json:"namespaces"`
+	Secrets []SecretDefinition `json:"secrets"`
+
+	// Namespaces holds regular expressions matched anywhere in the names of target namespaces.
+	// Deprecated: use NamespacePatterns, which can be anchored.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespacePatterns match the names of target namespaces, in addition to Namespaces.
+	NamespacePatterns []NamespacePattern `json:"namespacePatterns,omitempty"`
+
+	// NamespaceSelector selects target namespaces by their labels, in addition to those matching Namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ExcludeNamespaces holds patterns of namespaces that are never targeted, even when they are matched by
+	// Namespaces or NamespaceSelector.
+	ExcludeNamespaces []NamespacePattern `json:"excludeNamespaces,omitempty"`
+
+	// PrunePolicy tells what happens to the secrets of the Crypt once they are no longer defined by it, or their
+	// namespace no longer matches. Defaults to Delete.
+	PrunePolicy PrunePolicy `json:"prunePolicy,omitempty"`
+
+	// CreationPolicy tells what happens when a target namespace already holds a secret that the Crypt does not own.
+	// Defaults to Owner.
+	CreationPolicy CreationPolicy `json:"creationPolicy,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Delete;Retain
+type PrunePolicy string
+
+const (
+	// PrunePolicyDelete deletes the secrets that are no longer defined by the Crypt.
+	PrunePolicyDelete PrunePolicy = "Delete"
+	// PrunePolicyRetain leaves the secrets that are no longer defined by the Crypt in place.
+	PrunePolicyRetain PrunePolicy = "Retain"
+)
+
+func (in *CryptSpec) GetPrunePolicy() PrunePolicy {
+	if in.PrunePolicy == "" {
+		return PrunePolicyDelete
+	}
+	return in.PrunePolicy
+}
+
+// +kubebuilder:validation:Enum=Owner;Adopt;Merge;Orphan
+type CreationPolicy string
+
+const (
+	// CreationPolicyOwner creates and updates the secrets owned by the Crypt, and leaves any other secret of the
+	// same name untouched.
+	CreationPolicyOwner CreationPolicy = "Owner"
+	// CreationPolicyAdopt takes over existing secrets, which are then owned by the Crypt.
+	CreationPolicyAdopt CreationPolicy = "Adopt"
+	// CreationPolicyMerge writes the keys of the Crypt into existing secrets, which keep their other keys and are
+	// not owned by the Crypt.
+	CreationPolicyMerge CreationPolicy = "Merge"
+	// CreationPolicyOrphan behaves like Owner, but never deletes the secrets of the Crypt, which are left behind
+	// when the Crypt is deleted or no longer defines them.
+	CreationPolicyOrphan CreationPolicy = "Orphan"
+)
+
+func (in *CryptSpec) GetCreationPolicy() CreationPolicy {
+	if in.CreationPolicy == "" {
+		return CreationPolicyOwner
+	}
+	return in.CreationPolicy
+}
+
+// +kubebuilder:validation:Enum=Glob;Regex;Exact
+type NamespaceMatchType string
+
+const (
+	// NamespaceMatchGlob matches the whole name with a shell pattern, where * and ? match any characters.
+	NamespaceMatchGlob NamespaceMatchType = "Glob"
+	// NamespaceMatchRegex matches the whole name with a regular expression.
+	NamespaceMatchRegex NamespaceMatchType = "Regex"
+	// NamespaceMatchExact matches the name as is.
+	NamespaceMatchExact NamespaceMatchType = "Exact"
+)
+
+// NamespacePattern matches the names of namespaces.
+type NamespacePattern struct {
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+
+	// MatchType tells how Pattern is matched. Defaults to Glob.
+	MatchType NamespaceMatchType `json:"matchType,omitempty"`
+}
+
+func (in *NamespacePattern) GetMatchType() NamespaceMatchType {
+	if in.MatchType == "" {
+		return NamespaceMatchGlob
+	}
+	return in.MatchType
+}
+
+// Matcher compiles the pattern into a function matching namespace names.
+func (in *NamespacePattern) Matcher() (func(name string) bool, error) {
+	pattern := in.Pattern
+
+	switch in.GetMatchType() {
+	case NamespaceMatchGlob:
+		// namespace names have no separator, so path.Match matches them whole
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob namespace pattern %q: %v", pattern, err)
+		}
+		return func(name string) bool {
+			match, _ := path.Match(pattern, name)
+			return match
+		}, nil
+	case NamespaceMatchRegex:
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex namespace pattern %q: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	case NamespaceMatchExact:
+		return func(name string) bool {
+			return name == pattern
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown match type %q for namespace pattern %q", in.MatchType, pattern)
+	}
+}
+
+// SecretDefinition creates a secret from either a key or every key under a prefix.
 type SecretDefinition struct {
-	Name        string            `json:"name"`
-	Type        string            `json:"type"`
-	Key         string            `json:"key"`
-	Labels      map[string]string `json:"labels"`
+	// +optional
+	Name string `json:"name"`
+	// +optional
+	Type string `json:"type"`
+	// +optional
+	Key string `json:"key"`
+	// +optional
+	Labels map[string]string `json:"labels"`
+	// +optional
 	Annotations map[string]string `json:"annotations"`
+
+	// Store names the store the key is read from. The controller's default store is used when it is empty.
+	Store string `json:"store,omitempty"`
+
+	// Prefix is used instead of Key to create a secret for every key found under the prefix in the store.
+	Prefix string `json:"prefix,omitempty"`
+
+	// NameTemplate is a text/template rendering the name of the secret created for each key found under Prefix.
+	// It defaults to the path of the key relative to the prefix, made into a valid secret name.
+	NameTemplate string `json:"nameTemplate,omitempty"`
 }
 
 func (in *SecretDefinition) GetName() string {
@@ -58,6 +203,18 @@ func (in *SecretDefinition) GetKey() string {
 	return in.Key
 }
 
+func (in *SecretDefinition) GetStore() string {
+	return in.Store
+}
+
+func (in *SecretDefinition) GetPrefix() string {
+	return in.Prefix
+}
+
+func (in *SecretDefinition) GetNameTemplate() string {
+	return in.NameTemplate
+}
+
 func (in *SecretDefinition) GetLabels() map[string]string {
 	return in.Labels
 }
@@ -66,7 +223,85 @@ func (in *SecretDefinition) GetAnnotations() map[string]string {
 	return in.Annotations
 }
 
+// CryptStatus is the outcome of the last sync of a Crypt.
 type CryptStatus struct {
+	// ObservedGeneration is the generation of the Crypt spec the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions holds the Ready condition, which is true when every secret was synced or left to another Crypt, and
+	// the Conflict condition, which is true when another Crypt takes precedence for some of the secrets.
+	Conditions []CryptCondition `json:"conditions,omitempty"`
+
+	// LastSyncTime is when the Crypt was last synced.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Secrets lists the outcome of the last sync for each secret in each target namespace.
+	Secrets []SecretStatus `json:"secrets,omitempty"`
+
+	// SecretCount is the number of secrets, by name, the last sync wrote or tried to write.
+	SecretCount int `json:"secretCount,omitempty"`
+
+	// NamespaceCount is the number of namespaces the last sync wrote secrets to or tried to.
+	NamespaceCount int `json:"namespaceCount,omitempty"`
+}
+
+type CryptConditionType string
+
+const (
+	// CryptReady is true when every secret of the Crypt was synced to every target namespace.
+	CryptReady CryptConditionType = "Ready"
+	// CryptConflict is true when some secrets of the Crypt are not written because an older Crypt defines them in the
+	// same namespace.
+	CryptConflict CryptConditionType = "Conflict"
+)
+
+type CryptCondition struct {
+	Type               CryptConditionType `json:"type"`
+	Status             v1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time        `json:"lastTransitionTime,omitempty"`
+	Reason             string             `json:"reason,omitempty"`
+	Message            string             `json:"message,omitempty"`
+}
+
+// SecretStatus is the outcome of syncing a secret to a namespace. A secret whose key could not be resolved, such as
+// a prefix that could not be listed, has no namespace or name.
+type SecretStatus struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Key       string `json:"key"`
+	Synced    bool   `json:"synced"`
+	Error     string `json:"error,omitempty"`
+
+	// Reason tells why the secret could not be synced, such as StoreKeyNotFound or StoreUnavailable.
+	Reason string `json:"reason,omitempty"`
+
+	// ClaimedBy is the namespace/name of the Crypt that takes precedence for the secret, which is then not written.
+	ClaimedBy string `json:"claimedBy,omitempty"`
+}
+
+// GetCondition returns the condition of the given type, or nil when the status does not have it.
+func (in *CryptStatus) GetCondition(conditionType CryptConditionType) *CryptCondition {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type == conditionType {
+			return &in.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type. The transition time is kept when the status of the
+// condition does not change.
+func (in *CryptStatus) SetCondition(condition CryptCondition) {
+	existing := in.GetCondition(condition.Type)
+	if existing == nil {
+		in.Conditions = append(in.Conditions, condition)
+		return
+	}
+
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -78,3 +313,121 @@ type CryptList struct {
 
 	Items []Crypt `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SecretStore describes a connection to a store that Crypts in the same namespace can read from
+type SecretStore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SecretStoreSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SecretStoreList is a list of SecretStore resources
+type SecretStoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []SecretStore `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterSecretStore describes a connection to a store that Crypts in any namespace can read from
+type ClusterSecretStore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SecretStoreSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterSecretStoreList is a list of ClusterSecretStore resources
+type ClusterSecretStoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterSecretStore `json:"items"`
+}
+
+// SecretStoreSpec configures exactly one of the supported store backends.
+type SecretStoreSpec struct {
+	Consul *ConsulStoreSpec `json:"consul,omitempty"`
+	Vault  *VaultStoreSpec  `json:"vault,omitempty"`
+}
+
+type ConsulStoreSpec struct {
+	Address    string           `json:"address,omitempty"`
+	Scheme     string           `json:"scheme,omitempty"`
+	Datacenter string           `json:"datacenter,omitempty"`
+	Timeout    *metav1.Duration `json:"timeout,omitempty"`
+	TLS        *StoreTLSSpec    `json:"tls,omitempty"`
+
+	// TokenSecretRef references the ACL token used to read keys.
+	TokenSecretRef *SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+type VaultStoreSpec struct {
+	Address   string           `json:"address,omitempty"`
+	Namespace string           `json:"namespace,omitempty"`
+	Timeout   *metav1.Duration `json:"timeout,omitempty"`
+	TLS       *StoreTLSSpec    `json:"tls,omitempty"`
+
+	// Mount is the mount path of the KV secrets engine. Defaults to secret.
+	Mount string `json:"mount,omitempty"`
+	// KVVersion is the version of the KV secrets engine, detected when not set.
+	// +kubebuilder:validation:Enum=1;2
+	KVVersion int `json:"kvVersion,omitempty"`
+
+	Auth VaultAuthSpec `json:"auth"`
+}
+
+// VaultAuthSpec configures exactly one way of logging in to vault.
+type VaultAuthSpec struct {
+	TokenSecretRef *SecretKeySelector       `json:"tokenSecretRef,omitempty"`
+	Kubernetes     *VaultKubernetesAuthSpec `json:"kubernetes,omitempty"`
+	AppRole        *VaultAppRoleAuthSpec    `json:"appRole,omitempty"`
+}
+
+// VaultKubernetesAuthSpec logs in with the controller's service account token. It is only allowed on a
+// ClusterSecretStore.
+type VaultKubernetesAuthSpec struct {
+	Role      string `json:"role"`
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+type VaultAppRoleAuthSpec struct {
+	RoleID            string             `json:"roleID"`
+	SecretIDSecretRef *SecretKeySelector `json:"secretIDSecretRef,omitempty"`
+	MountPath         string             `json:"mountPath,omitempty"`
+}
+
+type StoreTLSSpec struct {
+	// CASecretRef references a PEM encoded CA bundle used to verify the server.
+	CASecretRef *SecretKeySelector `json:"caSecretRef,omitempty"`
+	// ClientCertSecretRef and ClientKeySecretRef reference a PEM encoded client certificate and key.
+	ClientCertSecretRef *SecretKeySelector `json:"clientCertSecretRef,omitempty"`
+	ClientKeySecretRef  *SecretKeySelector `json:"clientKeySecretRef,omitempty"`
+
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// SecretKeySelector selects a key of a Kubernetes Secret.
+type SecretKeySelector struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Namespace of the Secret. Only used by ClusterSecretStores; a SecretStore always reads Secrets from its
+	// own namespace.
+	Namespace string `json:"namespace,omitempty"`
+}
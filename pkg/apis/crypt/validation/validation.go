@@ -0,0 +1,148 @@
+// Package validation checks Crypts before they are synced. The controller and the validating webhook share it, so
+// that a Crypt rejected by one would never be accepted by the other.
+package validation
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metavalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var secretTypes = []string{
+	string(corev1.SecretTypeOpaque),
+	string(corev1.SecretTypeServiceAccountToken),
+	string(corev1.SecretTypeDockercfg),
+	string(corev1.SecretTypeDockerConfigJson),
+	string(corev1.SecretTypeBasicAuth),
+	string(corev1.SecretTypeSSHAuth),
+	string(corev1.SecretTypeTLS),
+	string(corev1.SecretTypeBootstrapToken),
+}
+
+var namespaceMatchTypes = []string{
+	string(v1alpha1.NamespaceMatchGlob),
+	string(v1alpha1.NamespaceMatchRegex),
+	string(v1alpha1.NamespaceMatchExact),
+}
+
+var creationPolicies = []string{
+	string(v1alpha1.CreationPolicyOwner),
+	string(v1alpha1.CreationPolicyAdopt),
+	string(v1alpha1.CreationPolicyMerge),
+	string(v1alpha1.CreationPolicyOrphan),
+}
+
+var prunePolicies = []string{
+	string(v1alpha1.PrunePolicyDelete),
+	string(v1alpha1.PrunePolicyRetain),
+}
+
+// ValidateCrypt returns every error in the spec of a Crypt.
+func ValidateCrypt(crypt *v1alpha1.Crypt) field.ErrorList {
+	return ValidateCryptSpec(&crypt.Spec, field.NewPath("spec"))
+}
+
+// ValidateCryptSpec returns every error in a Crypt spec, with paths below fldPath.
+func ValidateCryptSpec(spec *v1alpha1.CryptSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := sets.NewString()
+	for i := range spec.Secrets {
+		allErrs = append(allErrs, validateSecretDefinition(&spec.Secrets[i], names, fldPath.Child("secrets").Index(i))...)
+	}
+
+	for i, pattern := range spec.Namespaces {
+		if _, err := regexp.Compile(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaces").Index(i), pattern, err.Error()))
+		}
+	}
+	for i := range spec.NamespacePatterns {
+		allErrs = append(allErrs, validateNamespacePattern(&spec.NamespacePatterns[i], fldPath.Child("namespacePatterns").Index(i))...)
+	}
+	for i := range spec.ExcludeNamespaces {
+		allErrs = append(allErrs, validateNamespacePattern(&spec.ExcludeNamespaces[i], fldPath.Child("excludeNamespaces").Index(i))...)
+	}
+
+	if spec.NamespaceSelector != nil {
+		selectorErrs := metavalidation.ValidateLabelSelector(spec.NamespaceSelector, fldPath.Child("namespaceSelector"))
+		if len(selectorErrs) == 0 {
+			// the controller builds the selector this way, which checks more than the selector's structure
+			if _, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector); err != nil {
+				selectorErrs = append(selectorErrs, field.Invalid(fldPath.Child("namespaceSelector"), spec.NamespaceSelector, err.Error()))
+			}
+		}
+		allErrs = append(allErrs, selectorErrs...)
+	}
+
+	if spec.PrunePolicy != "" && !sets.NewString(prunePolicies...).Has(string(spec.PrunePolicy)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("prunePolicy"), spec.PrunePolicy, prunePolicies))
+	}
+	if spec.CreationPolicy != "" && !sets.NewString(creationPolicies...).Has(string(spec.CreationPolicy)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("creationPolicy"), spec.CreationPolicy, creationPolicies))
+	}
+
+	return allErrs
+}
+
+// validateSecretDefinition checks a secret definition, and that its name was not already seen among names.
+func validateSecretDefinition(sec *v1alpha1.SecretDefinition, names sets.String, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case sec.Key == "" && sec.Prefix == "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("key"), "either key or prefix is required"))
+	case sec.Key != "" && sec.Prefix != "":
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("prefix"), sec.Prefix, "key and prefix are mutually exclusive"))
+	case sec.Prefix != "":
+		// names of secrets created from a prefix are only known once its keys are listed
+		if sec.Name != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), sec.Name, "the names of secrets created from a prefix come from nameTemplate"))
+		}
+	default:
+		if sec.NameTemplate != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nameTemplate"), sec.NameTemplate, "nameTemplate is only used with a prefix"))
+		}
+		if sec.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+			break
+		}
+		for _, msg := range utilvalidation.IsDNS1123Subdomain(sec.Name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), sec.Name, msg))
+		}
+		if names.Has(sec.Name) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), sec.Name))
+		}
+		names.Insert(sec.Name)
+	}
+
+	// types of other tools are qualified with their domain, as kubernetes recommends
+	if sec.Type != "" && !strings.Contains(sec.Type, "/") && !sets.NewString(secretTypes...).Has(sec.Type) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), sec.Type, secretTypes))
+	}
+
+	allErrs = append(allErrs, metavalidation.ValidateLabels(sec.Labels, fldPath.Child("labels"))...)
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(sec.Annotations, fldPath.Child("annotations"))...)
+
+	return allErrs
+}
+
+func validateNamespacePattern(pattern *v1alpha1.NamespacePattern, fldPath *field.Path) field.ErrorList {
+	if pattern.Pattern == "" {
+		return field.ErrorList{field.Required(fldPath.Child("pattern"), "")}
+	}
+	if pattern.MatchType != "" && !sets.NewString(namespaceMatchTypes...).Has(string(pattern.MatchType)) {
+		return field.ErrorList{field.NotSupported(fldPath.Child("matchType"), pattern.MatchType, namespaceMatchTypes)}
+	}
+	if _, err := pattern.Matcher(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath.Child("pattern"), pattern.Pattern, err.Error())}
+	}
+	return nil
+}
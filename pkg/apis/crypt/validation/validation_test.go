@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+)
+
+func TestValidateCrypt(t *testing.T) {
+	valid := v1alpha1.CryptSpec{
+		Secrets: []v1alpha1.SecretDefinition{
+			{Name: "foo", Key: "dev/foo", Type: "kubernetes.io/tls"},
+			{Name: "bar", Key: "dev/bar", Type: "example.com/custom"},
+			{Prefix: "dev/apps/", NameTemplate: "app-{{ .Base }}"},
+		},
+		Namespaces:        []string{"^dev-"},
+		NamespacePatterns: []v1alpha1.NamespacePattern{{Pattern: "dev-*"}, {Pattern: "dev|test", MatchType: v1alpha1.NamespaceMatchRegex}},
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+		ExcludeNamespaces: []v1alpha1.NamespacePattern{{Pattern: "dev-sandbox", MatchType: v1alpha1.NamespaceMatchExact}},
+		PrunePolicy:       v1alpha1.PrunePolicyRetain,
+		CreationPolicy:    v1alpha1.CreationPolicyMerge,
+	}
+
+	tests := map[string]struct {
+		mutate func(spec *v1alpha1.CryptSpec)
+		fields []string
+	}{
+		"valid": {
+			mutate: func(spec *v1alpha1.CryptSpec) {},
+		},
+		"empty key": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Key = "" },
+			fields: []string{"spec.secrets[0].key"},
+		},
+		"key and prefix": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Prefix = "dev/" },
+			fields: []string{"spec.secrets[0].prefix"},
+		},
+		"named prefix": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[2].Name = "apps" },
+			fields: []string{"spec.secrets[2].name"},
+		},
+		"template without prefix": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].NameTemplate = "{{ .Base }}" },
+			fields: []string{"spec.secrets[0].nameTemplate"},
+		},
+		"missing name": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Name = "" },
+			fields: []string{"spec.secrets[0].name"},
+		},
+		"invalid name": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Name = "Foo_Bar" },
+			fields: []string{"spec.secrets[0].name"},
+		},
+		"duplicate name": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[1].Name = "foo" },
+			fields: []string{"spec.secrets[1].name"},
+		},
+		"unknown type": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Type = "tls" },
+			fields: []string{"spec.secrets[0].type"},
+		},
+		"invalid label": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Secrets[0].Labels = map[string]string{"team": "pay ments"} },
+			fields: []string{"spec.secrets[0].labels"},
+		},
+		"invalid regex": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.Namespaces = []string{"dev-("} },
+			fields: []string{"spec.namespaces[0]"},
+		},
+		"invalid patterns": {
+			mutate: func(spec *v1alpha1.CryptSpec) {
+				spec.NamespacePatterns = []v1alpha1.NamespacePattern{
+					{Pattern: "dev-["},
+					{Pattern: "dev-(", MatchType: v1alpha1.NamespaceMatchRegex},
+					{Pattern: "dev", MatchType: "Fuzzy"},
+					{},
+				}
+			},
+			fields: []string{
+				"spec.namespacePatterns[0].pattern",
+				"spec.namespacePatterns[1].pattern",
+				"spec.namespacePatterns[2].matchType",
+				"spec.namespacePatterns[3].pattern",
+			},
+		},
+		"invalid exclusion": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.ExcludeNamespaces[0].MatchType = "Fuzzy" },
+			fields: []string{"spec.excludeNamespaces[0].matchType"},
+		},
+		"invalid selector": {
+			mutate: func(spec *v1alpha1.CryptSpec) {
+				spec.NamespaceSelector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Near"}}
+			},
+			fields: []string{"spec.namespaceSelector.matchExpressions[0].operator"},
+		},
+		"unknown prune policy": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.PrunePolicy = "Keep" },
+			fields: []string{"spec.prunePolicy"},
+		},
+		"unknown creation policy": {
+			mutate: func(spec *v1alpha1.CryptSpec) { spec.CreationPolicy = "Overwrite" },
+			fields: []string{"spec.creationPolicy"},
+		},
+	}
+
+	for name, test := range tests {
+		crypt := &v1alpha1.Crypt{Spec: *valid.DeepCopy()}
+		test.mutate(&crypt.Spec)
+
+		errs := ValidateCrypt(crypt)
+		if len(errs) != len(test.fields) {
+			t.Errorf("%s: expected %d errors, got %v", name, len(test.fields), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Field != test.fields[i] {
+				t.Errorf("%s: expected error on %s, got %v", name, test.fields[i], err)
+			}
+		}
+	}
+}
@@ -27,7 +27,9 @@ import (
 
 type CoreV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterSecretStoresGetter
 	CryptsGetter
+	SecretStoresGetter
 }
 
 // CoreV1alpha1Client is used to interact with features provided by the core.bluehoodie.io group.
@@ -35,10 +37,18 @@ type CoreV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *CoreV1alpha1Client) ClusterSecretStores() ClusterSecretStoreInterface {
+	return newClusterSecretStores(c)
+}
+
 func (c *CoreV1alpha1Client) Crypts(namespace string) CryptInterface {
 	return newCrypts(c, namespace)
 }
 
+func (c *CoreV1alpha1Client) SecretStores(namespace string) SecretStoreInterface {
+	return newSecretStores(c, namespace)
+}
+
 // NewForConfig creates a new CoreV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*CoreV1alpha1Client, error) {
 	config := *c
@@ -28,10 +28,18 @@ type FakeCoreV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeCoreV1alpha1) ClusterSecretStores() v1alpha1.ClusterSecretStoreInterface {
+	return &FakeClusterSecretStores{c}
+}
+
 func (c *FakeCoreV1alpha1) Crypts(namespace string) v1alpha1.CryptInterface {
 	return &FakeCrypts{c, namespace}
 }
 
+func (c *FakeCoreV1alpha1) SecretStores(namespace string) v1alpha1.SecretStoreInterface {
+	return &FakeSecretStores{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeCoreV1alpha1) RESTClient() rest.Interface {
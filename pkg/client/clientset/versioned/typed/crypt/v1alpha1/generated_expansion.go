@@ -18,4 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type ClusterSecretStoreExpansion interface{}
+
 type CryptExpansion interface{}
+
+type SecretStoreExpansion interface{}
@@ -24,8 +24,12 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterSecretStores returns a ClusterSecretStoreInformer.
+	ClusterSecretStores() ClusterSecretStoreInformer
 	// Crypts returns a CryptInformer.
 	Crypts() CryptInformer
+	// SecretStores returns a SecretStoreInformer.
+	SecretStores() SecretStoreInformer
 }
 
 type version struct {
@@ -39,7 +43,17 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterSecretStores returns a ClusterSecretStoreInformer.
+func (v *version) ClusterSecretStores() ClusterSecretStoreInformer {
+	return &clusterSecretStoreInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Crypts returns a CryptInformer.
 func (v *version) Crypts() CryptInformer {
 	return &cryptInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// SecretStores returns a SecretStoreInformer.
+func (v *version) SecretStores() SecretStoreInformer {
+	return &secretStoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -53,8 +53,12 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=core.bluehoodie.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clustersecretstores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().ClusterSecretStores().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("crypts"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().Crypts().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("secretstores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1alpha1().SecretStores().Informer()}, nil
 
 	}
 
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha1
 
+// ClusterSecretStoreListerExpansion allows custom methods to be added to
+// ClusterSecretStoreLister.
+type ClusterSecretStoreListerExpansion interface{}
+
 // CryptListerExpansion allows custom methods to be added to
 // CryptLister.
 type CryptListerExpansion interface{}
@@ -25,3 +29,11 @@ type CryptListerExpansion interface{}
 // CryptNamespaceListerExpansion allows custom methods to be added to
 // CryptNamespaceLister.
 type CryptNamespaceListerExpansion interface{}
+
+// SecretStoreListerExpansion allows custom methods to be added to
+// SecretStoreLister.
+type SecretStoreListerExpansion interface{}
+
+// SecretStoreNamespaceListerExpansion allows custom methods to be added to
+// SecretStoreNamespaceLister.
+type SecretStoreNamespaceListerExpansion interface{}
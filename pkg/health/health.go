@@ -0,0 +1,72 @@
+// Package health serves the liveness and readiness checks of the controller to the kubelet.
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// LivePath is where the liveness checks are served.
+	LivePath = "/healthz"
+	// ReadyPath is where the readiness checks are served.
+	ReadyPath = "/readyz"
+)
+
+// Check is a named check that returns an error when it fails.
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// Handler runs every check on each request, and answers 200 when they all pass or 503 otherwise, listing the
+// outcome of each check.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		failed := false
+		for _, check := range checks {
+			if err := check.Check(); err != nil {
+				failed = true
+				fmt.Fprintf(&body, "[-]%s failed: %v\n", check.Name, err)
+				continue
+			}
+			fmt.Fprintf(&body, "[+]%s ok\n", check.Name)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if failed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			body.WriteString("check failed\n")
+		} else {
+			body.WriteString("ok\n")
+		}
+		body.WriteTo(w)
+	})
+}
+
+// Serve serves the liveness checks on LivePath and the readiness checks on ReadyPath until stop is closed.
+func Serve(addr string, live, ready []Check, stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(LivePath, Handler(live...))
+	mux.Handle(ReadyPath, Handler(ready...))
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	passing := Check{Name: "informers", Check: func() error { return nil }}
+	failing := Check{Name: "stores", Check: func() error { return fmt.Errorf("connection refused") }}
+
+	tests := map[string]struct {
+		checks []Check
+		code   int
+		body   []string
+	}{
+		"passing": {
+			checks: []Check{passing},
+			code:   http.StatusOK,
+			body:   []string{"[+]informers ok", "ok"},
+		},
+		"failing": {
+			checks: []Check{passing, failing},
+			code:   http.StatusServiceUnavailable,
+			body:   []string{"[+]informers ok", "[-]stores failed: connection refused", "check failed"},
+		},
+	}
+
+	for name, test := range tests {
+		recorder := httptest.NewRecorder()
+		Handler(test.checks...).ServeHTTP(recorder, httptest.NewRequest("GET", ReadyPath, nil))
+
+		if recorder.Code != test.code {
+			t.Errorf("%s: expected status %d, got %d", name, test.code, recorder.Code)
+		}
+		for _, line := range test.body {
+			if !strings.Contains(recorder.Body.String(), line) {
+				t.Errorf("%s: expected %q in body:\n%s", name, line, recorder.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package metrics holds the Prometheus metrics of the controller, and serves them along with those of its work
+// queues.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
+
+const namespace = "crypt_controller"
+
+// Path is where the metrics are served.
+const Path = "/metrics"
+
+// Outcomes of a sync.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Operations on secrets.
+const (
+	SecretCreated   = "created"
+	SecretUpdated   = "updated"
+	SecretUnchanged = "unchanged"
+	SecretDeleted   = "deleted"
+)
+
+// Registry holds every metric of the controller.
+var Registry = prometheus.NewRegistry()
+
+var (
+	reconciles = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Number of syncs of each Crypt, by result.",
+	}, []string{"crypt", "result"})).(*prometheus.CounterVec)
+
+	reconcileDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "How long syncing each Crypt took, by result.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"crypt", "result"})).(*prometheus.HistogramVec)
+
+	storeReadDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_get_duration_seconds",
+		Help:      "How long reading a key from a store took, by backend.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"backend"})).(*prometheus.HistogramVec)
+
+	storeErrors = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_get_errors_total",
+		Help:      "Number of failed reads from a store, by backend and kind of error.",
+	}, []string{"backend", "kind"})).(*prometheus.CounterVec)
+
+	secretOperations = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secrets_total",
+		Help:      "Number of secrets created, updated, found unchanged or deleted.",
+	}, []string{"operation"})).(*prometheus.CounterVec)
+
+	lastSuccess = register(newLastSuccessCollector()).(*lastSuccessCollector)
+)
+
+func init() {
+	Registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	Registry.MustRegister(prometheus.NewGoCollector())
+}
+
+// register adds a collector to the registry, and returns the one already registered in its place if any, so that
+// creating several controllers, as tests do, does not fail.
+func register(c prometheus.Collector) prometheus.Collector {
+	if err := Registry.Register(c); err != nil {
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return existing.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+// ObserveReconcile records a sync of the Crypt with the given key.
+func ObserveReconcile(crypt string, duration time.Duration, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+	reconciles.WithLabelValues(crypt, result).Inc()
+	reconcileDuration.WithLabelValues(crypt, result).Observe(duration.Seconds())
+
+	if err == nil {
+		lastSuccess.set(crypt, time.Now())
+	}
+}
+
+// ForgetCrypt drops the metrics of a Crypt that was deleted.
+func ForgetCrypt(crypt string) {
+	for _, result := range []string{ResultSuccess, ResultError} {
+		reconciles.DeleteLabelValues(crypt, result)
+		reconcileDuration.DeleteLabelValues(crypt, result)
+	}
+	lastSuccess.delete(crypt)
+}
+
+// ObserveStoreGet records a read from a store.
+func ObserveStoreGet(s store.Store, duration time.Duration, err error) {
+	backend := store.BackendName(s)
+	storeReadDuration.WithLabelValues(backend).Observe(duration.Seconds())
+	if err != nil {
+		storeErrors.WithLabelValues(backend, errorKind(err)).Inc()
+	}
+}
+
+// SecretOperation counts an operation on a secret.
+func SecretOperation(operation string) {
+	secretOperations.WithLabelValues(operation).Inc()
+}
+
+func errorKind(err error) string {
+	switch errors.Cause(err) {
+	case store.NotFoundError:
+		return "not_found"
+	case store.InvalidDataError:
+		return "invalid_data"
+	case store.AuthenticationError:
+		return "authentication"
+	case store.PermissionDeniedError:
+		return "permission_denied"
+	case store.UnavailableError:
+		return "unavailable"
+	case context.DeadlineExceeded:
+		return "timeout"
+	case context.Canceled:
+		return "canceled"
+	}
+	return "other"
+}
+
+// Handler serves the metrics of the registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Serve serves the metrics on addr until stop is closed.
+func Serve(addr string, stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// lastSuccessCollector reports how long ago each Crypt was last synced successfully, computed when scraped.
+type lastSuccessCollector struct {
+	desc *prometheus.Desc
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newLastSuccessCollector() *lastSuccessCollector {
+	return &lastSuccessCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "seconds_since_last_success"),
+			"Seconds since the last successful sync of each Crypt.", []string{"crypt"}, nil),
+		last: make(map[string]time.Time),
+	}
+}
+
+func (c *lastSuccessCollector) set(crypt string, t time.Time) {
+	c.mu.Lock()
+	c.last[crypt] = t
+	c.mu.Unlock()
+}
+
+func (c *lastSuccessCollector) delete(crypt string) {
+	c.mu.Lock()
+	delete(c.last, crypt)
+	c.mu.Unlock()
+}
+
+func (c *lastSuccessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *lastSuccessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for crypt, t := range c.last {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(t).Seconds(), crypt)
+	}
+}
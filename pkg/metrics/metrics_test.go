@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"github.com/bluehoodie/crypt-controller/pkg/store/memory"
+)
+
+// collected returns the number of metrics a collector reports.
+func collected(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestReconcileMetrics(t *testing.T) {
+	ObserveReconcile("default/test-crypt", time.Second, nil)
+	ObserveReconcile("default/test-crypt", time.Second, fmt.Errorf("failed"))
+
+	for _, result := range []string{ResultSuccess, ResultError} {
+		if n := testutil.ToFloat64(reconciles.WithLabelValues("default/test-crypt", result)); n != 1 {
+			t.Errorf("expected 1 %s sync, got %v", result, n)
+		}
+	}
+	if n := collected(lastSuccess); n != 1 {
+		t.Errorf("expected the last success of 1 crypt, got %d", n)
+	}
+
+	ForgetCrypt("default/test-crypt")
+	if n := collected(lastSuccess); n != 0 {
+		t.Errorf("expected the last success of no crypt, got %d", n)
+	}
+	if n := collected(reconciles); n != 0 {
+		t.Errorf("expected no sync counts, got %d", n)
+	}
+}
+
+func TestStoreErrorKinds(t *testing.T) {
+	s, _ := memory.New(nil)
+
+	tests := map[string]error{
+		"not_found":         errors.Wrap(store.NotFoundError, "crypt/dev/foo"),
+		"invalid_data":      store.InvalidDataError,
+		"permission_denied": errors.Wrap(store.PermissionDeniedError, "vault request failed"),
+		"unavailable":       errors.Wrap(store.UnavailableError, "consul request failed"),
+		"timeout":           context.DeadlineExceeded,
+		"other":             fmt.Errorf("connection refused"),
+	}
+	for kind, err := range tests {
+		before := testutil.ToFloat64(storeErrors.WithLabelValues("memory", kind))
+		ObserveStoreGet(s, time.Millisecond, err)
+		if n := testutil.ToFloat64(storeErrors.WithLabelValues("memory", kind)) - before; n != 1 {
+			t.Errorf("expected 1 %s error, got %v", kind, n)
+		}
+	}
+}
+
+func TestWorkqueueMetrics(t *testing.T) {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-queue")
+	defer queue.ShutDown()
+
+	depth := testutil.ToFloat64(queueDepth.WithLabelValues("test-queue"))
+	adds := testutil.ToFloat64(queueAdds.WithLabelValues("test-queue"))
+
+	queue.Add("default/test-crypt")
+	if n := testutil.ToFloat64(queueDepth.WithLabelValues("test-queue")) - depth; n != 1 {
+		t.Errorf("expected the depth to grow by 1, got %v", n)
+	}
+	if n := testutil.ToFloat64(queueAdds.WithLabelValues("test-queue")) - adds; n != 1 {
+		t.Errorf("expected 1 add, got %v", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	SecretOperation(SecretCreated)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", Path, nil))
+
+	body, _ := ioutil.ReadAll(recorder.Body)
+	if !strings.Contains(string(body), `crypt_controller_secrets_total{operation="created"}`) {
+		t.Errorf("expected the secrets created to be served, got:\n%s", body)
+	}
+}
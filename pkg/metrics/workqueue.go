@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const workqueueSubsystem = "workqueue"
+
+var (
+	queueDepth = register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "depth",
+		Help:      "Number of items waiting in each work queue.",
+	}, []string{"name"})).(*prometheus.GaugeVec)
+
+	queueAdds = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "adds_total",
+		Help:      "Number of items added to each work queue.",
+	}, []string{"name"})).(*prometheus.CounterVec)
+
+	queueLatency = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "queue_duration_seconds",
+		Help:      "How long items stay in each work queue before they are processed.",
+		Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+	}, []string{"name"})).(*prometheus.HistogramVec)
+
+	queueWorkDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "work_duration_seconds",
+		Help:      "How long processing an item from each work queue takes.",
+		Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+	}, []string{"name"})).(*prometheus.HistogramVec)
+
+	queueUnfinishedWork = register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "unfinished_work_seconds",
+		Help:      "Seconds of work in progress that has not been observed by work_duration yet, for each work queue.",
+	}, []string{"name"})).(*prometheus.GaugeVec)
+
+	queueLongestRunning = register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "longest_running_processor_seconds",
+		Help:      "How long the longest running processor of each work queue has been running.",
+	}, []string{"name"})).(*prometheus.GaugeVec)
+
+	queueRetries = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: workqueueSubsystem,
+		Name:      "retries_total",
+		Help:      "Number of retries handled by each work queue.",
+	}, []string{"name"})).(*prometheus.CounterVec)
+)
+
+// the provider is set before any queue is created, as queues only pick it up when they are created
+func init() {
+	workqueue.SetProvider(queueMetricsProvider{})
+}
+
+// queueMetricsProvider exports the metrics of the work queues. the deprecated metrics are not exported.
+type queueMetricsProvider struct{}
+
+func (queueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return queueDepth.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return queueAdds.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return queueLatency.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return queueWorkDuration.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return queueUnfinishedWork.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return queueLongestRunning.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return queueRetries.WithLabelValues(name)
+}
+
+func (queueMetricsProvider) NewDeprecatedDepthMetric(name string) workqueue.GaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedAddsMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedLatencyMetric(name string) workqueue.SummaryMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedWorkDurationMetric(name string) workqueue.SummaryMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedLongestRunningProcessorMicrosecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewDeprecatedRetriesMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
@@ -1,18 +1,45 @@
 package consul
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/bluehoodie/crypt-controller/pkg/store"
 
 	"github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+	log "k8s.io/klog"
+)
+
+const (
+	// DefaultWaitTime is how long a blocking query waits for a key to change before it is issued again.
+	DefaultWaitTime = 5 * time.Minute
+
+	// watchRetryInterval is how long to wait before retrying a blocking query that failed.
+	watchRetryInterval = 5 * time.Second
 )
 
 type Store struct {
-	client *api.Client
+	client   *api.Client
+	waitTime time.Duration
 }
 
-func New(config *api.Config) (store.Store, error) {
+type Option func(*Store)
+
+// WithWaitTime sets how long blocking queries wait for a change. it must be shorter than the timeout of the
+// http client, if there is one.
+func WithWaitTime(waitTime time.Duration) Option {
+	return func(s *Store) {
+		s.waitTime = waitTime
+	}
+}
+
+func New(config *api.Config, opts ...Option) (store.Store, error) {
 	if config == nil {
 		config = api.DefaultConfig()
 	}
@@ -22,13 +49,26 @@ func New(config *api.Config) (store.Store, error) {
 		return nil, err
 	}
 
-	return &Store{client: client}, nil
+	s := &Store{
+		client:   client,
+		waitTime: DefaultWaitTime,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
-func (s *Store) Get(key string) (store.Object, error) {
-	pair, _, err := s.client.KV().Get(key, nil)
+func (s *Store) Backend() string {
+	return "consul"
+}
+
+func (s *Store) Get(ctx context.Context, key string) (store.Object, error) {
+	pair, _, err := s.client.KV().Get(key, (&api.QueryOptions{}).WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, storeError(ctx, err)
 	}
 
 	if pair == nil {
@@ -43,3 +83,127 @@ func (s *Store) Get(key string) (store.Object, error) {
 
 	return store.Object(obj), nil
 }
+
+// Ping asks consul for its leader, which fails when the agent cannot be reached or the cluster has lost its quorum.
+func (s *Store) Ping(ctx context.Context) error {
+	// Status().Leader does not take query options in this version of the consul api
+	result := make(chan error, 1)
+	go func() {
+		leader, err := s.client.Status().Leader()
+		if err != nil {
+			err = storeError(ctx, err)
+		} else if leader == "" {
+			err = errors.Wrap(store.UnavailableError, "consul cluster has no leader")
+		}
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, _, err := s.client.KV().Keys(prefix, "", (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		// keys ending with a slash are folders created by the consul ui and hold no value
+		if !strings.HasSuffix(key, "/") {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// Watch uses blocking queries to wait for the key to change. a change is reported when the modify index of the
+// key moves, which includes the key being created or deleted.
+func (s *Store) Watch(ctx context.Context, key string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		var index, modifyIndex uint64
+		known := false
+
+		for {
+			opts := (&api.QueryOptions{WaitIndex: index, WaitTime: s.waitTime}).WithContext(ctx)
+			pair, meta, err := s.client.KV().Get(key, opts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Warningf("could not watch consul key %s: %v", key, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(watchRetryInterval):
+				}
+				continue
+			}
+
+			switch {
+			case meta.LastIndex < index:
+				// the index goes backwards when the consul state is restored from a snapshot
+				index = 0
+			case meta.LastIndex == 0:
+				// a zero index would turn the next query into a non-blocking one
+				index = 1
+			default:
+				index = meta.LastIndex
+			}
+
+			var current uint64
+			if pair != nil {
+				current = pair.ModifyIndex
+			}
+			if known && current != modifyIndex {
+				notify(changes)
+			}
+			modifyIndex, known = current, true
+		}
+	}()
+
+	return changes
+}
+
+// storeError wraps the error of a failed request around the store error matching its status code, which the consul
+// api only reports in the message. requests that got no response did not reach the agent.
+func storeError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var code int
+	if _, scanErr := fmt.Sscanf(err.Error(), "Unexpected response code: %d", &code); scanErr != nil {
+		return errors.Wrapf(store.UnavailableError, "consul request failed: %v", err)
+	}
+
+	switch {
+	case code == http.StatusForbidden && strings.Contains(err.Error(), "ACL not found"):
+		// the token is unknown to consul, rather than missing a policy
+		return errors.Wrapf(store.AuthenticationError, "consul request failed: %v", err)
+	case code == http.StatusUnauthorized:
+		return errors.Wrapf(store.AuthenticationError, "consul request failed: %v", err)
+	case code == http.StatusForbidden:
+		return errors.Wrapf(store.PermissionDeniedError, "consul request failed: %v", err)
+	}
+	return errors.Wrapf(store.UnavailableError, "consul request failed: %v", err)
+}
+
+func notify(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+		// a change is already pending
+	}
+}
@@ -1,6 +1,10 @@
 package empty
 
-import "github.com/bluehoodie/crypt-controller/pkg/store"
+import (
+	"context"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
 
 type Store struct {
 }
@@ -9,6 +13,13 @@ func New() (store.Store, error) {
 	return &Store{}, nil
 }
 
-func (*Store) Get(key string) (store.Object, error) {
+func (*Store) Backend() string {
+	return "empty"
+}
+
+func (*Store) Get(ctx context.Context, key string) (store.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, store.NotFoundError
 }
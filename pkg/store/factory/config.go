@@ -0,0 +1,334 @@
+package factory
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigVersionV1 is the only version of the store configuration format currently understood.
+const ConfigVersionV1 = "v1"
+
+// Config is the store configuration file. It may be written in YAML or JSON.
+type Config struct {
+	APIVersion string `json:"apiVersion"`
+
+	Consul *ConsulConfig `json:"consul,omitempty"`
+	Vault  *VaultConfig  `json:"vault,omitempty"`
+
+	// Stores lists named store instances that secrets select with their store field.
+	// When it is empty a single store of the type given on the command line is built from the sections above.
+	Stores []StoreConfig `json:"stores,omitempty"`
+	// DefaultStore names the store used by secrets that do not select one. It may be omitted when there is a
+	// single store.
+	DefaultStore string `json:"defaultStore,omitempty"`
+}
+
+type StoreConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	Consul *ConsulConfig `json:"consul,omitempty"`
+	Vault  *VaultConfig  `json:"vault,omitempty"`
+}
+
+type TLSConfig struct {
+	CAFile             string `json:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+
+	// CAData, CertData and KeyData hold PEM encoded certificates in place of the files above.
+	CAData   string `json:"caData,omitempty"`
+	CertData string `json:"certData,omitempty"`
+	KeyData  string `json:"keyData,omitempty"`
+}
+
+type ConsulConfig struct {
+	Address    string          `json:"address,omitempty"`
+	Scheme     string          `json:"scheme,omitempty"`
+	Datacenter string          `json:"datacenter,omitempty"`
+	Token      string          `json:"token,omitempty"`
+	TokenFile  string          `json:"tokenFile,omitempty"`
+	Timeout    metav1.Duration `json:"timeout,omitempty"`
+	TLS        *TLSConfig      `json:"tls,omitempty"`
+}
+
+type VaultConfig struct {
+	Address   string          `json:"address,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Timeout   metav1.Duration `json:"timeout,omitempty"`
+	TLS       *TLSConfig      `json:"tls,omitempty"`
+
+	KV   VaultKVConfig   `json:"kv,omitempty"`
+	Auth VaultAuthConfig `json:"auth,omitempty"`
+}
+
+type VaultKVConfig struct {
+	Mount   string `json:"mount,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+type VaultAuthConfig struct {
+	// Method is one of token, kubernetes or approle. Defaults to token.
+	Method    string `json:"method,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
+
+	// token
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+
+	// kubernetes
+	Role                    string `json:"role,omitempty"`
+	ServiceAccountTokenPath string `json:"serviceAccountTokenPath,omitempty"`
+
+	// approle
+	RoleID       string `json:"roleID,omitempty"`
+	SecretID     string `json:"secretID,omitempty"`
+	SecretIDFile string `json:"secretIDFile,omitempty"`
+}
+
+// LoadConfig reads and validates the store configuration file at the given path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read store config")
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, errors.Wrapf(err, "could not parse store config %s", path)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid store config %s", path)
+	}
+
+	return config, nil
+}
+
+// configFromEnv builds the configuration used when no config file is given, from the environment variables
+// supported before config files were introduced. Addresses, tokens and TLS settings are still read from the
+// environment by the consul and vault client libraries themselves.
+func configFromEnv() (*Config, error) {
+	config := &Config{
+		APIVersion: ConfigVersionV1,
+		Consul:     &ConsulConfig{},
+		Vault: &VaultConfig{
+			KV: VaultKVConfig{
+				Mount: os.Getenv(VaultKVMountEnv),
+			},
+			Auth: VaultAuthConfig{
+				Method:                  strings.TrimSpace(strings.ToLower(os.Getenv(VaultAuthMethodEnv))),
+				MountPath:               os.Getenv(VaultAuthMountEnv),
+				Role:                    os.Getenv(VaultAuthRoleEnv),
+				ServiceAccountTokenPath: os.Getenv(VaultAuthTokenPathEnv),
+				RoleID:                  os.Getenv(VaultRoleIDEnv),
+				SecretID:                os.Getenv(VaultSecretIDEnv),
+			},
+		},
+	}
+
+	if v := os.Getenv(VaultKVVersionEnv); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", VaultKVVersionEnv)
+		}
+		config.Vault.KV.Version = version
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid store environment")
+	}
+
+	return config, nil
+}
+
+// Validate reports every problem found in the configuration at once.
+func (c *Config) Validate() error {
+	var allErrs field.ErrorList
+
+	if c.APIVersion != ConfigVersionV1 {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{ConfigVersionV1}))
+	}
+
+	if c.Consul != nil {
+		allErrs = append(allErrs, c.Consul.validate(field.NewPath("consul"))...)
+	}
+
+	if c.Vault != nil {
+		allErrs = append(allErrs, c.Vault.validate(field.NewPath("vault"))...)
+	}
+
+	names := make(map[string]struct{})
+	for i := range c.Stores {
+		path := field.NewPath("stores").Index(i)
+		allErrs = append(allErrs, c.Stores[i].validate(path)...)
+
+		if _, ok := names[c.Stores[i].Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), c.Stores[i].Name))
+		}
+		names[c.Stores[i].Name] = struct{}{}
+	}
+
+	if c.DefaultStore != "" {
+		if _, ok := names[c.DefaultStore]; !ok {
+			allErrs = append(allErrs, field.NotFound(field.NewPath("defaultStore"), c.DefaultStore))
+		}
+	} else if len(c.Stores) > 1 {
+		allErrs = append(allErrs, field.Required(field.NewPath("defaultStore"), "required when more than one store is configured"))
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// defaultStoreName returns the name of the store used by secrets that do not select one.
+func (c *Config) defaultStoreName() string {
+	if c.DefaultStore == "" && len(c.Stores) == 1 {
+		return c.Stores[0].Name
+	}
+	return c.DefaultStore
+}
+
+// Validate reports every problem found in the configuration of a single store.
+func (c *StoreConfig) Validate() error {
+	return c.validate(field.NewPath("store")).ToAggregate()
+}
+
+func (c *StoreConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if c.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(c.Name) {
+			allErrs = append(allErrs, field.Invalid(path.Child("name"), c.Name, msg))
+		}
+	}
+
+	switch strings.TrimSpace(strings.ToLower(c.Type)) {
+	case ConsulStoreType:
+		if c.Vault != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("vault"), "may not be set for a consul store"))
+		}
+	case VaultStoreType:
+		if c.Consul != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("consul"), "may not be set for a vault store"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), c.Type, []string{ConsulStoreType, VaultStoreType}))
+	}
+
+	if c.Consul != nil {
+		allErrs = append(allErrs, c.Consul.validate(path.Child("consul"))...)
+	}
+	if c.Vault != nil {
+		allErrs = append(allErrs, c.Vault.validate(path.Child("vault"))...)
+	}
+
+	return allErrs
+}
+
+func (c *ConsulConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch c.Scheme {
+	case "", "http", "https":
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("scheme"), c.Scheme, []string{"http", "https"}))
+	}
+
+	if c.Token != "" && c.TokenFile != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("tokenFile"), "may not be set together with token"))
+	}
+
+	if c.Timeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeout"), c.Timeout.Duration.String(), "must not be negative"))
+	}
+
+	allErrs = append(allErrs, c.TLS.validate(path.Child("tls"))...)
+
+	return allErrs
+}
+
+func (c *VaultConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if c.Timeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeout"), c.Timeout.Duration.String(), "must not be negative"))
+	}
+
+	switch c.KV.Version {
+	case 0, 1, 2:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("kv", "version"), c.KV.Version, []string{"1", "2"}))
+	}
+
+	allErrs = append(allErrs, c.TLS.validate(path.Child("tls"))...)
+
+	authPath := path.Child("auth")
+	auth := c.Auth
+	switch auth.Method {
+	case "", VaultTokenAuth:
+		if auth.Token != "" && auth.TokenFile != "" {
+			allErrs = append(allErrs, field.Forbidden(authPath.Child("tokenFile"), "may not be set together with token"))
+		}
+	case VaultKubernetesAuth:
+		if auth.Role == "" {
+			allErrs = append(allErrs, field.Required(authPath.Child("role"), "required for the kubernetes auth method"))
+		}
+	case VaultAppRoleAuth:
+		if auth.RoleID == "" {
+			allErrs = append(allErrs, field.Required(authPath.Child("roleID"), "required for the approle auth method"))
+		}
+		if auth.SecretID != "" && auth.SecretIDFile != "" {
+			allErrs = append(allErrs, field.Forbidden(authPath.Child("secretIDFile"), "may not be set together with secretID"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(authPath.Child("method"), auth.Method,
+			[]string{VaultTokenAuth, VaultKubernetesAuth, VaultAppRoleAuth}))
+	}
+
+	return allErrs
+}
+
+func (c *TLSConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if c == nil {
+		return allErrs
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		allErrs = append(allErrs, field.Required(path.Child("keyFile"), "certFile and keyFile must be set together"))
+	}
+
+	if (c.CertData == "") != (c.KeyData == "") {
+		allErrs = append(allErrs, field.Required(path.Child("keyData"), "certData and keyData must be set together"))
+	}
+
+	if c.CAFile != "" && c.CAData != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("caData"), "may not be set together with caFile"))
+	}
+
+	if c.CertFile != "" && c.CertData != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("certData"), "may not be set together with certFile"))
+	}
+
+	return allErrs
+}
+
+func readFileValue(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,88 @@
+package factory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "store-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: v1
+vault:
+  address: https://vault:8200
+  timeout: 15s
+  kv:
+    mount: kv
+    version: 2
+  auth:
+    method: kubernetes
+    role: crypt-controller
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Vault == nil || config.Vault.Address != "https://vault:8200" {
+		t.Fatalf("vault address not loaded: %+v", config.Vault)
+	}
+	if config.Vault.Timeout.Duration != 15*time.Second {
+		t.Errorf("expected 15s timeout, got %v", config.Vault.Timeout.Duration)
+	}
+	if config.Vault.KV.Mount != "kv" || config.Vault.KV.Version != 2 {
+		t.Errorf("unexpected kv config %+v", config.Vault.KV)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: v2
+vault:
+  kv:
+    version: 3
+  auth:
+    method: kubernetes
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, field := range []string{"apiVersion", "vault.kv.version", "vault.auth.role"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected error to mention %s, got %v", field, err)
+		}
+	}
+}
+
+func TestLoadConfigUnknownField(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: v1
+vault:
+  adress: https://vault:8200
+`)
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
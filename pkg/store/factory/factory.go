@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/bluehoodie/crypt-controller/pkg/store"
@@ -17,21 +18,266 @@ const (
 	VaultStoreType  = "vault"
 )
 
+const (
+	// VaultKVMountEnv names the mount path of the vault KV secrets engine.
+	VaultKVMountEnv = "VAULT_KV_MOUNT"
+	// VaultKVVersionEnv names the version (1 or 2) of the vault KV secrets engine. It is detected when unset.
+	VaultKVVersionEnv = "VAULT_KV_VERSION"
+
+	// VaultAuthMethodEnv names the method used to log in to vault: token (the default), kubernetes or approle.
+	VaultAuthMethodEnv = "VAULT_AUTH_METHOD"
+	// VaultAuthMountEnv names the path the auth method is enabled at, when not the method's default.
+	VaultAuthMountEnv = "VAULT_AUTH_MOUNT"
+	// VaultAuthRoleEnv names the vault role to log in as with the kubernetes auth method.
+	VaultAuthRoleEnv = "VAULT_AUTH_ROLE"
+	// VaultAuthTokenPathEnv names the service account token file used by the kubernetes auth method.
+	VaultAuthTokenPathEnv = "VAULT_AUTH_TOKEN_PATH"
+	// VaultRoleIDEnv and VaultSecretIDEnv hold the credentials used by the approle auth method.
+	VaultRoleIDEnv   = "VAULT_ROLE_ID"
+	VaultSecretIDEnv = "VAULT_SECRET_ID"
+)
+
+const (
+	VaultTokenAuth      = "token"
+	VaultKubernetesAuth = "kubernetes"
+	VaultAppRoleAuth    = "approle"
+)
+
 type Factory struct {
 	config string
 }
 
+// Make builds a store of the given type, configured from the factory's config file or,
+// when there is none, from the environment.
 func (f *Factory) Make(storeType string) (store.Store, error) {
+	config, err := f.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return makeStore(storeType, config.Consul, config.Vault)
+}
+
+// MakeRegistry builds every store listed in the config file. Without a list of stores it builds a single
+// default store of the given type, named after that type, or no store at all when no type is given.
+func (f *Factory) MakeRegistry(storeType string) (*store.Registry, error) {
+	config, err := f.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Stores) == 0 {
+		if storeType == "" {
+			// stores are all described by SecretStore and ClusterSecretStore resources
+			return store.NewRegistry(""), nil
+		}
+
+		s, err := makeStore(storeType, config.Consul, config.Vault)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSpace(strings.ToLower(storeType))
+		registry := store.NewRegistry(name)
+		registry.Register(name, s)
+		return registry, nil
+	}
+
+	registry := store.NewRegistry(config.defaultStoreName())
+	for _, sc := range config.Stores {
+		s, err := makeStore(sc.Type, sc.Consul, sc.Vault)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not initialize store %q", sc.Name)
+		}
+		registry.Register(sc.Name, s)
+	}
+
+	return registry, nil
+}
+
+// NewStore builds a single store from its configuration.
+func NewStore(c StoreConfig) (store.Store, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return makeStore(c.Type, c.Consul, c.Vault)
+}
+
+func makeStore(storeType string, consulConfig *ConsulConfig, vaultConfig *VaultConfig) (store.Store, error) {
 	switch strings.TrimSpace(strings.ToLower(storeType)) {
 	case ConsulStoreType:
-		return consul.New(consulapi.DefaultConfig())
+		return newConsulStore(consulConfig)
 	case VaultStoreType:
-		return vault.New(vaultapi.DefaultConfig())
+		return newVaultStore(vaultConfig)
 	default:
-		return nil, errors.New("invalid store type")
+		return nil, errors.Errorf("invalid store type %q", storeType)
 	}
 }
 
 func NewStoreFactory(configFilePath string) *Factory {
 	return &Factory{config: configFilePath}
 }
+
+func (f *Factory) loadConfig() (*Config, error) {
+	if f.config == "" {
+		return configFromEnv()
+	}
+	return LoadConfig(f.config)
+}
+
+func newConsulStore(c *ConsulConfig) (store.Store, error) {
+	config := consulapi.DefaultConfig()
+	if c == nil {
+		return consul.New(config)
+	}
+
+	if c.Address != "" {
+		config.Address = c.Address
+	}
+	if c.Scheme != "" {
+		config.Scheme = c.Scheme
+	}
+	if c.Datacenter != "" {
+		config.Datacenter = c.Datacenter
+	}
+	if c.Token != "" {
+		config.Token = c.Token
+	}
+	if c.TokenFile != "" {
+		config.TokenFile = c.TokenFile
+	}
+
+	if c.TLS != nil {
+		config.TLSConfig = consulapi.TLSConfig{
+			Address:            c.TLS.ServerName,
+			CAFile:             c.TLS.CAFile,
+			CertFile:           c.TLS.CertFile,
+			KeyFile:            c.TLS.KeyFile,
+			InsecureSkipVerify: c.TLS.InsecureSkipVerify,
+		}
+	}
+
+	if c.TLS.hasData() {
+		tlsConf, err := consulapi.SetupTLSConfig(&config.TLSConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not configure consul tls")
+		}
+		if err := c.TLS.applyData(tlsConf); err != nil {
+			return nil, errors.Wrap(err, "could not configure consul tls")
+		}
+		config.Transport.TLSClientConfig = tlsConf
+	}
+
+	if c.Timeout.Duration > 0 || c.TLS.hasData() {
+		httpClient, err := consulapi.NewHttpClient(config.Transport, config.TLSConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not configure consul tls")
+		}
+		httpClient.Timeout = c.Timeout.Duration
+		config.HttpClient = httpClient
+	}
+
+	var opts []consul.Option
+	if c.Timeout.Duration > 0 {
+		// blocking queries have to return before the http client gives up on them
+		opts = append(opts, consul.WithWaitTime(c.Timeout.Duration/2))
+	}
+
+	return consul.New(config, opts...)
+}
+
+func newVaultStore(c *VaultConfig) (store.Store, error) {
+	config := vaultapi.DefaultConfig()
+	if config.Error != nil {
+		return nil, errors.Wrap(config.Error, "could not read vault environment")
+	}
+
+	if c == nil {
+		return vault.New(config)
+	}
+
+	if c.Address != "" {
+		config.Address = c.Address
+	}
+	if c.Timeout.Duration > 0 {
+		config.Timeout = c.Timeout.Duration
+	}
+
+	if c.TLS != nil {
+		err := config.ConfigureTLS(&vaultapi.TLSConfig{
+			CACert:        c.TLS.CAFile,
+			ClientCert:    c.TLS.CertFile,
+			ClientKey:     c.TLS.KeyFile,
+			TLSServerName: c.TLS.ServerName,
+			Insecure:      c.TLS.InsecureSkipVerify,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not configure vault tls")
+		}
+
+		if c.TLS.hasData() {
+			transport := config.HttpClient.Transport.(*http.Transport)
+			if err := c.TLS.applyData(transport.TLSClientConfig); err != nil {
+				return nil, errors.Wrap(err, "could not configure vault tls")
+			}
+		}
+	}
+
+	opts := []vault.Option{
+		vault.WithKVVersion(c.KV.Version),
+	}
+	if c.KV.Mount != "" {
+		opts = append(opts, vault.WithMount(c.KV.Mount))
+	}
+	if c.Namespace != "" {
+		opts = append(opts, vault.WithNamespace(c.Namespace))
+	}
+
+	authOpt, err := vaultAuthOption(c.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if authOpt != nil {
+		opts = append(opts, authOpt)
+	}
+
+	return vault.New(config, opts...)
+}
+
+func vaultAuthOption(c VaultAuthConfig) (vault.Option, error) {
+	switch c.Method {
+	case "", VaultTokenAuth:
+		token := c.Token
+		if c.TokenFile != "" {
+			var err error
+			if token, err = readFileValue(c.TokenFile); err != nil {
+				return nil, errors.Wrap(err, "could not read vault token file")
+			}
+		}
+		if token == "" {
+			return nil, nil
+		}
+		return vault.WithToken(token), nil
+	case VaultKubernetesAuth:
+		return vault.WithAuth(&vault.KubernetesAuth{
+			Role:      c.Role,
+			MountPath: c.MountPath,
+			TokenPath: c.ServiceAccountTokenPath,
+		}), nil
+	case VaultAppRoleAuth:
+		secretID := c.SecretID
+		if c.SecretIDFile != "" {
+			var err error
+			if secretID, err = readFileValue(c.SecretIDFile); err != nil {
+				return nil, errors.Wrap(err, "could not read vault approle secret id file")
+			}
+		}
+		return vault.WithAuth(&vault.AppRoleAuth{
+			RoleID:    c.RoleID,
+			SecretID:  secretID,
+			MountPath: c.MountPath,
+		}), nil
+	default:
+		return nil, errors.Errorf("invalid vault auth method %q", c.Method)
+	}
+}
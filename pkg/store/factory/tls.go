@@ -0,0 +1,34 @@
+package factory
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+)
+
+// hasData reports whether PEM data has to be added to the tls configuration built by the client libraries,
+// which only know how to load certificates from files.
+func (c *TLSConfig) hasData() bool {
+	return c != nil && (c.CAData != "" || c.CertData != "")
+}
+
+func (c *TLSConfig) applyData(tlsConf *tls.Config) error {
+	if c.CAData != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CAData)) {
+			return errors.New("could not parse ca data")
+		}
+		tlsConf.RootCAs = pool
+	}
+
+	if c.CertData != "" {
+		cert, err := tls.X509KeyPair([]byte(c.CertData), []byte(c.KeyData))
+		if err != nil {
+			return errors.Wrap(err, "could not parse client certificate")
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
+	return nil
+}
@@ -1,6 +1,12 @@
 package memory
 
-import "github.com/bluehoodie/crypt-controller/pkg/store"
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+)
 
 type Store struct {
 	m map[string]store.Object
@@ -14,10 +20,34 @@ func New(m map[string]store.Object) (store.Store, error) {
 	return &s, nil
 }
 
-func (s *Store) Get(key string) (store.Object, error) {
+func (s *Store) Backend() string {
+	return "memory"
+}
+
+// Get and List fail like the other stores once the context is done, so that timeouts can be tested against them.
+func (s *Store) Get(ctx context.Context, key string) (store.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	v, ok := s.m[key]
 	if !ok {
 		return nil, store.NotFoundError
 	}
 	return v, nil
 }
+
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range s.m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var UnknownStoreError = errors.New("store not found")
+
+// Registry holds the named stores that secrets can be read from.
+// Secrets that do not name a store are read from the default store.
+type Registry struct {
+	mu           sync.RWMutex
+	stores       map[string]Store
+	defaultStore string
+}
+
+func NewRegistry(defaultStore string) *Registry {
+	return &Registry{
+		stores:       make(map[string]Store),
+		defaultStore: defaultStore,
+	}
+}
+
+// Register adds a store under the given name, closing any store it replaces.
+func (r *Registry) Register(name string, s Store) {
+	r.mu.Lock()
+	old, ok := r.stores[name]
+	r.stores[name] = s
+	r.mu.Unlock()
+
+	if ok && old != s {
+		closeStore(old)
+	}
+}
+
+// Unregister removes and closes the store with the given name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	old, ok := r.stores[name]
+	delete(r.stores, name)
+	r.mu.Unlock()
+
+	if ok {
+		closeStore(old)
+	}
+}
+
+// Get returns the store with the given name, or the default store when name is empty.
+func (r *Registry) Get(name string) (Store, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.defaultStore
+	}
+
+	s, ok := r.stores[name]
+	if !ok {
+		return nil, errors.Wrapf(UnknownStoreError, "%q", name)
+	}
+	return s, nil
+}
+
+// Names returns the sorted names of the registered stores.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.stores))
+	for name := range r.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func closeStore(s Store) {
+	if c, ok := s.(io.Closer); ok {
+		c.Close()
+	}
+}
@@ -1,16 +1,65 @@
 package store
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
+// the errors of a store are wrapped around one of these, so that Cause tells what went wrong whatever the backend.
 var (
-	NotFoundError    = errors.New("key not found")
-	InvalidDataError = errors.New("value could not be decoded into a store object")
+	NotFoundError         = errors.New("key not found")
+	InvalidDataError      = errors.New("value could not be decoded into a store object")
+	AuthenticationError   = errors.New("could not authenticate with store")
+	PermissionDeniedError = errors.New("permission denied by store")
+	UnavailableError      = errors.New("store unavailable")
 )
 
+// IsPermanent reports whether an error of a store will happen again until the store or the request is changed, as
+// opposed to an outage that retrying the request may get past. unknown errors are not permanent.
+func IsPermanent(err error) bool {
+	switch errors.Cause(err) {
+	case NotFoundError, InvalidDataError, AuthenticationError, PermissionDeniedError:
+		return true
+	}
+	return false
+}
+
+// Store reads secret data. Reads give up once the context is done.
 type Store interface {
-	Get(key string) (Object, error)
+	Get(ctx context.Context, key string) (Object, error)
+}
+
+// Watcher is implemented by stores that can report changes to the value of a key.
+type Watcher interface {
+	// Watch sends on the returned channel every time the value of the key changes, until the context is done.
+	// changes made in quick succession may be reported once. the channel is closed when the watch stops.
+	Watch(ctx context.Context, key string) <-chan struct{}
+}
+
+// Lister is implemented by stores that can enumerate their keys.
+type Lister interface {
+	// List returns the sorted keys found under the prefix, at any depth.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Pinger is implemented by stores that can check they are reachable without reading a key.
+type Pinger interface {
+	// Ping returns an error when the store cannot serve reads, until the context is done.
+	Ping(ctx context.Context) error
+}
+
+// Backend is implemented by stores to name the kind of store they read from, such as consul or vault.
+type Backend interface {
+	Backend() string
+}
+
+// BackendName returns the kind of store s reads from, or unknown when it does not say.
+func BackendName(s Store) string {
+	if b, ok := s.(Backend); ok {
+		return b.Backend()
+	}
+	return "unknown"
 }
 
 type Object map[string][]byte
@@ -0,0 +1,279 @@
+package vault
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog"
+)
+
+const (
+	// DefaultKubernetesMount is the path the kubernetes auth method is enabled at by default.
+	DefaultKubernetesMount = "kubernetes"
+	// DefaultServiceAccountTokenPath is where the pod's service account token is mounted.
+	DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// DefaultAppRoleMount is the path the approle auth method is enabled at by default.
+	DefaultAppRoleMount = "approle"
+)
+
+// Authenticator logs in to vault and returns the secret holding the resulting client token.
+type Authenticator interface {
+	Login(client *api.Client) (*api.Secret, error)
+}
+
+// KubernetesAuth logs in with the kubernetes auth method using a service account token.
+type KubernetesAuth struct {
+	Role      string
+	MountPath string
+	TokenPath string
+}
+
+func (a *KubernetesAuth) Login(client *api.Client) (*api.Secret, error) {
+	tokenPath := a.TokenPath
+	if tokenPath == "" {
+		tokenPath = DefaultServiceAccountTokenPath
+	}
+
+	// the token is read on every login as projected service account tokens are rotated by the kubelet.
+	jwt, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read service account token")
+	}
+
+	return client.Logical().Write(loginPath(a.MountPath, DefaultKubernetesMount), map[string]interface{}{
+		"role": a.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+// AppRoleAuth logs in with the approle auth method.
+type AppRoleAuth struct {
+	RoleID    string
+	SecretID  string
+	MountPath string
+}
+
+func (a *AppRoleAuth) Login(client *api.Client) (*api.Secret, error) {
+	data := map[string]interface{}{
+		"role_id": a.RoleID,
+	}
+	if a.SecretID != "" {
+		data["secret_id"] = a.SecretID
+	}
+
+	return client.Logical().Write(loginPath(a.MountPath, DefaultAppRoleMount), data)
+}
+
+func loginPath(mount, defaultMount string) string {
+	mount = strings.Trim(mount, "/")
+	if mount == "" {
+		mount = defaultMount
+	}
+	return path.Join("auth", mount, "login")
+}
+
+// WithAuth makes the store log in with the given authenticator instead of using the token from the environment.
+func WithAuth(auth Authenticator) Option {
+	return func(s *Store) {
+		s.auth = auth
+	}
+}
+
+// authenticate obtains the initial token and starts keeping it alive in the background.
+func (s *Store) authenticate() error {
+	var secret *api.Secret
+	var err error
+
+	if s.auth != nil {
+		secret, err = s.login()
+	} else {
+		secret, err = s.lookupToken()
+	}
+	if err != nil {
+		return err
+	}
+
+	go s.keepAlive(secret)
+	return nil
+}
+
+func (s *Store) login() (*api.Secret, error) {
+	// login endpoints must not be called with a stale token
+	s.client.ClearToken()
+
+	secret, err := s.auth.Login(s.client)
+	if err != nil {
+		return nil, errors.Wrapf(store.AuthenticationError, "vault login failed: %v", err)
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, errors.Wrap(store.AuthenticationError, "vault login returned no client token")
+	}
+
+	s.client.SetToken(secret.Auth.ClientToken)
+	return secret, nil
+}
+
+// lookupToken renews the token configured in the environment, returning it as an auth secret the renewer can manage.
+// a token that cannot be renewed is returned with its remaining ttl, so that it is reported once it expires.
+func (s *Store) lookupToken() (*api.Secret, error) {
+	if s.client.Token() == "" {
+		return nil, errors.Wrap(store.AuthenticationError, "no vault token configured")
+	}
+
+	secret, err := s.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, errors.Wrapf(store.AuthenticationError, "vault token lookup failed: %v", err)
+	}
+
+	renewable, _ := secret.TokenIsRenewable()
+	if !renewable {
+		ttl, err := secret.TokenTTL()
+		if err != nil {
+			return nil, errors.Wrapf(store.AuthenticationError, "invalid vault token ttl: %v", err)
+		}
+		return &api.Secret{Auth: &api.SecretAuth{
+			ClientToken:   s.client.Token(),
+			LeaseDuration: int(ttl / time.Second),
+		}}, nil
+	}
+
+	secret, err = s.client.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return nil, errors.Wrapf(store.AuthenticationError, "vault token renewal failed: %v", err)
+	}
+
+	return secret, nil
+}
+
+// keepAlive renews the token until it reaches its max TTL or renewal fails, then logs in again.
+// tokens that cannot be obtained again are only renewed; once they expire reads fail with an authentication error.
+func (s *Store) keepAlive(secret *api.Secret) {
+	for {
+		if secret == nil || secret.Auth == nil {
+			return
+		}
+
+		expires, err := s.watchToken(secret)
+		if err != nil {
+			log.Warningf("vault token renewal stopped: %v", err)
+		}
+
+		if s.auth == nil {
+			// the token keeps working until it actually expires
+			select {
+			case <-s.stopCh:
+				return
+			case <-s.clock.After(expires.Sub(s.clock.Now())):
+			}
+			s.setAuthError(errors.Wrap(store.AuthenticationError, "vault token expired and cannot be renewed"))
+			return
+		}
+
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		secret = s.relogin()
+	}
+}
+
+// watchToken blocks until the token can no longer be renewed or the store is closed, and returns when the token
+// expires.
+func (s *Store) watchToken(secret *api.Secret) (time.Time, error) {
+	expires := s.clock.Now().Add(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+
+	if !secret.Auth.Renewable {
+		// a non-renewable token is replaced shortly before it expires. tokens without a ttl never expire.
+		ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+		if ttl == 0 {
+			<-s.stopCh
+			return expires, nil
+		}
+
+		select {
+		case <-s.stopCh:
+		case <-s.clock.After(ttl * 2 / 3):
+		}
+		return expires, nil
+	}
+
+	renewer, err := s.client.NewRenewer(&api.RenewerInput{Secret: secret})
+	if err != nil {
+		return expires, err
+	}
+	defer renewer.Stop()
+
+	go renewer.Renew()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return expires, nil
+		case err := <-renewer.DoneCh():
+			return expires, err
+		case renewal := <-renewer.RenewCh():
+			log.V(4).Infof("renewed vault token at %v", renewal.RenewedAt)
+			if renewal.Secret != nil && renewal.Secret.Auth != nil {
+				expires = renewal.RenewedAt.Add(time.Duration(renewal.Secret.Auth.LeaseDuration) * time.Second)
+			}
+		}
+	}
+}
+
+// relogin retries logging in with exponential backoff until it succeeds or the store is closed.
+func (s *Store) relogin() *api.Secret {
+	backoff := wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    10,
+		Cap:      2 * time.Minute,
+	}
+
+	for {
+		select {
+		case <-s.stopCh:
+			return nil
+		default:
+		}
+
+		secret, err := s.login()
+		if err == nil {
+			s.setAuthError(nil)
+			return secret
+		}
+
+		log.Errorf("could not log in to vault: %v", err)
+		s.setAuthError(err)
+
+		delay := backoff.Step()
+		select {
+		case <-s.stopCh:
+			return nil
+		case <-s.clock.After(delay):
+		}
+	}
+}
+
+func (s *Store) setAuthError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authErr = err
+}
+
+func (s *Store) authError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authErr
+}
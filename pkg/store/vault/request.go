@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// read and list behave like Logical().Read and Logical().List, which do not take a context in this version of the
+// vault api.
+func (s *Store) read(ctx context.Context, path string) (*api.Secret, error) {
+	return s.do(ctx, s.client.NewRequest("GET", "/v1/"+path))
+}
+
+func (s *Store) list(ctx context.Context, path string) (*api.Secret, error) {
+	r := s.client.NewRequest("LIST", "/v1/"+path)
+	// older servers only understand the list parameter
+	r.Method = "GET"
+	r.Params.Set("list", "true")
+	return s.do(ctx, r)
+}
+
+// Ping checks the health of the vault server, which fails when it is sealed or not initialized, and that the store
+// could authenticate with it. standby servers forward reads to the active one and are fine.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.authError(); err != nil {
+		return err
+	}
+
+	r := s.client.NewRequest("GET", "/v1/sys/health")
+	r.Params.Set("standbyok", "true")
+	r.Params.Set("perfstandbyok", "true")
+
+	resp, err := s.client.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return storeError(ctx, resp, err)
+	}
+	return nil
+}
+
+func (s *Store) do(ctx context.Context, r *api.Request) (*api.Secret, error) {
+	resp, err := s.client.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	// a missing path is answered with a 404 that may still carry warnings, or the metadata of a deleted kv v2 version
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		secret, parseErr := api.ParseSecret(resp.Body)
+		if parseErr != nil || secret == nil || (len(secret.Warnings) == 0 && len(secret.Data) == 0) {
+			return nil, nil
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, storeError(ctx, resp, err)
+	}
+
+	return api.ParseSecret(resp.Body)
+}
+
+// storeError wraps the error of a failed request around the store error matching its status code. requests that got
+// no response did not reach vault.
+func storeError(ctx context.Context, resp *api.Response, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if resp == nil {
+		return errors.Wrapf(store.UnavailableError, "vault request failed: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return errors.Wrapf(store.AuthenticationError, "vault request failed: %v", err)
+	case http.StatusForbidden:
+		// vault also answers 403 for a token that expired, was revoked or never existed
+		if tokenError(err) {
+			return errors.Wrapf(store.AuthenticationError, "vault request failed: %v", err)
+		}
+		return errors.Wrapf(store.PermissionDeniedError, "vault request failed: %v", err)
+	}
+	// vault answers 503 when it is sealed and other 5xx codes when it failed
+	return errors.Wrapf(store.UnavailableError, "vault request failed: %v", err)
+}
+
+// tokenError reports whether a failed request was refused because of the token rather than its policies. the error
+// of the vault api lists the errors of the response body.
+func tokenError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "invalid token") || strings.Contains(msg, "token expired")
+}
@@ -1,18 +1,95 @@
 package vault
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bluehoodie/crypt-controller/pkg/store"
 
 	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+const (
+	// DefaultMount is the mount path of the KV secrets engine enabled by default on a Vault server.
+	DefaultMount = "secret"
+
+	// KVVersionAuto detects the version of the KV secrets engine from the mount configuration.
+	KVVersionAuto = 0
+	KVVersion1    = 1
+	KVVersion2    = 2
+
+	// DefaultPollInterval is how often watched keys are checked for changes.
+	DefaultPollInterval = 30 * time.Second
 )
 
 type Store struct {
 	client *api.Client
+	auth   Authenticator
+
+	mount     string
+	namespace string
+	token     string
+
+	pollInterval time.Duration
+	// clock times the renewal and expiry of the token
+	clock clock.Clock
+
+	mu        sync.Mutex
+	kvVersion int
+	authErr   error
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
 }
 
-func New(config *api.Config) (store.Store, error) {
+type Option func(*Store)
+
+// WithMount sets the mount path of the KV secrets engine that keys are read from.
+func WithMount(mount string) Option {
+	return func(s *Store) {
+		s.mount = mount
+	}
+}
+
+// WithKVVersion sets the version of the KV secrets engine mounted at the mount path.
+// KVVersionAuto, the default, queries Vault for the version on first use.
+func WithKVVersion(version int) Option {
+	return func(s *Store) {
+		s.kvVersion = version
+	}
+}
+
+// WithNamespace sets the vault enterprise namespace requests are made in.
+func WithNamespace(namespace string) Option {
+	return func(s *Store) {
+		s.namespace = namespace
+	}
+}
+
+// WithToken sets the token used when no other auth method is configured, instead of the one from the environment.
+func WithToken(token string) Option {
+	return func(s *Store) {
+		s.token = token
+	}
+}
+
+// WithPollInterval sets how often watched keys are checked for changes.
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.pollInterval = interval
+	}
+}
+
+func New(config *api.Config, opts ...Option) (store.Store, error) {
 	if config == nil {
 		config = api.DefaultConfig()
 	}
@@ -22,11 +99,71 @@ func New(config *api.Config) (store.Store, error) {
 		return nil, err
 	}
 
-	return &Store{client: client}, nil
+	s := &Store{
+		client:       client,
+		mount:        DefaultMount,
+		pollInterval: DefaultPollInterval,
+		clock:        clock.RealClock{},
+		stopCh:       make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mount = strings.Trim(s.mount, "/")
+	if s.mount == "" {
+		return nil, errors.New("vault mount path must not be empty")
+	}
+
+	if s.pollInterval <= 0 {
+		return nil, errors.New("vault poll interval must be positive")
+	}
+
+	switch s.kvVersion {
+	case KVVersionAuto, KVVersion1, KVVersion2:
+	default:
+		return nil, errors.Errorf("unsupported kv secrets engine version %d", s.kvVersion)
+	}
+
+	if s.namespace != "" {
+		client.SetNamespace(s.namespace)
+	}
+
+	if s.token != "" {
+		client.SetToken(s.token)
+	}
+
+	if err := s.authenticate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
 }
 
-func (s *Store) Get(key string) (store.Object, error) {
-	secret, err := s.client.Logical().Read(fmt.Sprintf("secret/data/%s", key))
+// Close stops renewing the vault token and watching keys.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	return nil
+}
+
+func (s *Store) Backend() string {
+	return "vault"
+}
+
+func (s *Store) Get(ctx context.Context, key string) (store.Object, error) {
+	if err := s.authError(); err != nil {
+		return nil, err
+	}
+
+	version, err := s.version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.read(ctx, s.dataPath(version, key))
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +172,18 @@ func (s *Store) Get(key string) (store.Object, error) {
 		return nil, store.NotFoundError
 	}
 
-	obj, err := dataToObject(secret.Data)
+	data := secret.Data
+	if version == KVVersion2 {
+		// kv v2 wraps the secret in an envelope alongside its version metadata.
+		// deleted and destroyed versions come back with a nil data field.
+		inner, ok := data["data"].(map[string]interface{})
+		if !ok || inner == nil {
+			return nil, store.NotFoundError
+		}
+		data = inner
+	}
+
+	obj, err := dataToObject(data)
 	if err != nil {
 		return nil, err
 	}
@@ -43,16 +191,152 @@ func (s *Store) Get(key string) (store.Object, error) {
 	return obj, nil
 }
 
+// List walks the folders under the prefix. kv v2 keys are listed from the metadata of the engine.
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := s.authError(); err != nil {
+		return nil, err
+	}
+
+	version, err := s.version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if err := s.walk(ctx, version, strings.Trim(prefix, "/"), &keys); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *Store) walk(ctx context.Context, version int, folder string, keys *[]string) error {
+	listPath := path.Join(s.mount, folder)
+	if version == KVVersion2 {
+		listPath = path.Join(s.mount, "metadata", folder)
+	}
+
+	secret, err := s.list(ctx, listPath)
+	if err != nil {
+		return err
+	}
+
+	// vault answers a list of an empty or missing folder with no secret
+	if secret == nil || secret.Data == nil {
+		return nil
+	}
+
+	entries, _ := secret.Data["keys"].([]interface{})
+	for _, entry := range entries {
+		name, ok := entry.(string)
+		if !ok {
+			continue
+		}
+
+		if strings.HasSuffix(name, "/") {
+			if err := s.walk(ctx, version, path.Join(folder, name), keys); err != nil {
+				return err
+			}
+			continue
+		}
+		*keys = append(*keys, path.Join(folder, name))
+	}
+
+	return nil
+}
+
+func (s *Store) dataPath(version int, key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if version == KVVersion2 {
+		return path.Join(s.mount, "data", key)
+	}
+	return path.Join(s.mount, key)
+}
+
+// version returns the version of the KV secrets engine, detecting it the first time it is needed.
+// a failed detection is not cached so that it is retried on the next read.
+func (s *Store) version(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	version := s.kvVersion
+	s.mu.Unlock()
+
+	if version != KVVersionAuto {
+		return version, nil
+	}
+
+	// the mutex also guards the auth error, which must not wait for the request. concurrent reads may both detect
+	// the version, and get the same one.
+	version, err := s.detectVersion(ctx)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not detect kv secrets engine version for mount %q", s.mount)
+	}
+
+	s.mu.Lock()
+	s.kvVersion = version
+	s.mu.Unlock()
+	return version, nil
+}
+
+// detectVersion uses the same preflight endpoint as the vault CLI to read the mount options.
+func (s *Store) detectVersion(ctx context.Context) (int, error) {
+	secret, err := s.read(ctx, path.Join("sys/internal/ui/mounts", s.mount))
+	if err != nil {
+		return 0, err
+	}
+
+	// servers older than 0.10 do not have the endpoint and only support kv v1.
+	if secret == nil || secret.Data == nil {
+		return KVVersion1, nil
+	}
+
+	options, _ := secret.Data["options"].(map[string]interface{})
+	if options == nil {
+		return KVVersion1, nil
+	}
+
+	switch fmt.Sprint(options["version"]) {
+	case "2":
+		return KVVersion2, nil
+	default:
+		return KVVersion1, nil
+	}
+}
+
 func dataToObject(data map[string]interface{}) (store.Object, error) {
 	o := store.Object(make(map[string][]byte))
 
 	for key, value := range data {
-		bytes, ok := value.([]byte)
-		if !ok {
-			return nil, store.InvalidDataError
+		bytes, err := valueToBytes(value)
+		if err != nil {
+			return nil, err
 		}
 		o[key] = bytes
 	}
 
 	return o, nil
 }
+
+// valueToBytes converts the scalar json values vault returns into secret data.
+func valueToBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case json.Number:
+		return []byte(v.String()), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case bool:
+		return []byte(strconv.FormatBool(v)), nil
+	default:
+		return nil, store.InvalidDataError
+	}
+}
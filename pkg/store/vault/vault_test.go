@@ -0,0 +1,221 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestDataPath(t *testing.T) {
+	s := &Store{mount: "kv/team"}
+
+	if p := s.dataPath(KVVersion1, "crypt/dev/foo"); p != "kv/team/crypt/dev/foo" {
+		t.Errorf("unexpected kv v1 path %q", p)
+	}
+
+	if p := s.dataPath(KVVersion2, "/crypt/dev/foo"); p != "kv/team/data/crypt/dev/foo" {
+		t.Errorf("unexpected kv v2 path %q", p)
+	}
+}
+
+func TestDataToObject(t *testing.T) {
+	data := map[string]interface{}{
+		"username": "admin",
+		"port":     json.Number("5432"),
+		"ratio":    0.5,
+		"enabled":  true,
+		"raw":      []byte("raw"),
+	}
+
+	obj, err := dataToObject(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := store.Object(map[string][]byte{
+		"username": []byte("admin"),
+		"port":     []byte("5432"),
+		"ratio":    []byte("0.5"),
+		"enabled":  []byte("true"),
+		"raw":      []byte("raw"),
+	})
+	if !reflect.DeepEqual(expected, obj) {
+		t.Errorf("expected %v, got %v", expected, obj)
+	}
+
+	_, err = dataToObject(map[string]interface{}{"nested": map[string]interface{}{"a": "b"}})
+	if err != store.InvalidDataError {
+		t.Errorf("expected InvalidDataError for nested values, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/health" || r.URL.Query().Get("standbyok") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{client: client}
+
+	status = http.StatusOK
+	if err := s.Ping(context.Background()); err != nil {
+		t.Errorf("expected an active server to be reachable, got %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := s.Ping(context.Background()); errors.Cause(err) != store.UnavailableError {
+		t.Errorf("expected a sealed server to be unavailable, got %v", err)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{client: client}
+
+	tests := []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{http.StatusUnauthorized, `{"errors":["failed"]}`, store.AuthenticationError},
+		{http.StatusForbidden, `{"errors":["permission denied"]}`, store.PermissionDeniedError},
+		{http.StatusForbidden, `{"errors":["2 errors occurred:\n\t* permission denied\n\t* invalid token\n\n"]}`, store.AuthenticationError},
+		{http.StatusForbidden, `{"errors":["token expired"]}`, store.AuthenticationError},
+		{http.StatusInternalServerError, `{"errors":["failed"]}`, store.UnavailableError},
+		{http.StatusServiceUnavailable, `{"errors":["failed"]}`, store.UnavailableError},
+	}
+	for _, test := range tests {
+		status, body = test.status, test.body
+		if _, err := s.read(context.Background(), "secret/foo"); errors.Cause(err) != test.expected {
+			t.Errorf("%d %s: expected %v, got %v", test.status, test.body, test.expected, err)
+		}
+	}
+
+	server.Close()
+	if _, err := s.read(context.Background(), "secret/foo"); errors.Cause(err) != store.UnavailableError {
+		t.Errorf("expected an unreachable server to be unavailable, got %v", err)
+	}
+}
+
+func TestStaticTokenReportedOnceExpired(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	s := &Store{clock: fakeClock, stopCh: make(chan struct{})}
+	defer close(s.stopCh)
+
+	secret := &api.Secret{Auth: &api.SecretAuth{ClientToken: "static", LeaseDuration: 60}}
+	go s.keepAlive(secret)
+
+	// step waits for the store to wait on the clock, then moves it forward
+	step := func(d time.Duration) {
+		err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return fakeClock.HasWaiters(), nil
+		})
+		if err != nil {
+			t.Fatal("the store is not waiting for the token to expire")
+		}
+		fakeClock.Step(d)
+	}
+
+	// a token that cannot be renewed or obtained again works until the end of its ttl
+	step(50 * time.Second)
+	step(5 * time.Second)
+	if err := s.authError(); err != nil {
+		t.Fatalf("expected the token to be valid before it expires, got %v", err)
+	}
+
+	step(5 * time.Second)
+	err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return s.authError() != nil, nil
+	})
+	if err != nil || errors.Cause(s.authError()) != store.AuthenticationError {
+		t.Errorf("expected an authentication error once the token expired, got %v", s.authError())
+	}
+}
+
+func TestLookupStaticToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"data":{"ttl":3600,"renewable":false,"expire_time":"2019-03-01T13:00:00Z"}}`))
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("static")
+	s := &Store{client: client}
+
+	// a token that cannot be renewed is still tracked until it expires
+	secret, err := s.lookupToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.Renewable || secret.Auth.LeaseDuration != 3600 {
+		t.Errorf("expected a non-renewable token expiring in an hour, got %+v", secret)
+	}
+}
+
+func TestVersionDetectionDoesNotBlockAuthError(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte(`{"data":{"options":{"version":"2"}}}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{client: client, mount: "secret"}
+
+	go s.version(context.Background())
+	<-started
+
+	// the health checks ask for the auth error while the version of a slow server is being detected
+	done := make(chan struct{})
+	go func() {
+		s.authError()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("auth error blocked on the version detection")
+	}
+}
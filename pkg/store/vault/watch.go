@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluehoodie/crypt-controller/pkg/store"
+
+	log "k8s.io/klog"
+)
+
+// Watch polls the key and reports a change whenever its revision differs from the one seen on the previous poll.
+func (s *Store) Watch(ctx context.Context, key string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+
+		var last string
+		known := false
+
+		for {
+			rev, err := s.revision(ctx, key)
+			if err != nil {
+				log.Warningf("could not watch vault key %s: %v", key, err)
+			} else {
+				if known && rev != last {
+					select {
+					case changes <- struct{}{}:
+					default:
+						// a change is already pending
+					}
+				}
+				last, known = rev, true
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return changes
+}
+
+// revision returns a value that changes whenever the secret at key changes. kv v2 keeps the current version of
+// a secret in its metadata, which is cheaper to read than the secret. kv v1 secrets, and v2 secrets whose metadata
+// the token may not read, are read and hashed instead. a missing secret has an empty revision.
+func (s *Store) revision(ctx context.Context, key string) (string, error) {
+	if err := s.authError(); err != nil {
+		return "", err
+	}
+
+	version, err := s.version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if version == KVVersion2 {
+		secret, err := s.read(ctx, s.metadataPath(key))
+		if err == nil {
+			if secret == nil || secret.Data == nil {
+				return "", nil
+			}
+			return metadataRevision(secret.Data), nil
+		}
+		log.V(4).Infof("could not read vault metadata for %s, comparing data instead: %v", key, err)
+	}
+
+	obj, err := s.Get(ctx, key)
+	if err == store.NotFoundError {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return objectDigest(obj), nil
+}
+
+func (s *Store) metadataPath(key string) string {
+	return path.Join(s.mount, "metadata", strings.TrimPrefix(key, "/"))
+}
+
+// metadataRevision combines the current version of a kv v2 secret with its deletion state, as deleting or
+// destroying the current version does not change the version number.
+func metadataRevision(data map[string]interface{}) string {
+	current := fmt.Sprint(data["current_version"])
+
+	versions, _ := data["versions"].(map[string]interface{})
+	meta, _ := versions[current].(map[string]interface{})
+
+	return fmt.Sprintf("%s/%v/%v", current, meta["deletion_time"], meta["destroyed"])
+}
+
+func objectDigest(obj store.Object) string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(obj[k])
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/retry"
+	log "k8s.io/klog"
+)
+
+// RenewBefore is how long before it expires the serving certificate is replaced.
+const RenewBefore = 30 * 24 * time.Hour
+
+// EnsureCertificate returns the serving certificate of the webhook for the given service, along with its key. the
+// certificate is self-signed and kept in a secret, so that every replica serves the same one. a new one is generated
+// when the secret does not exist yet, or holds a certificate that is invalid or about to expire.
+func EnsureCertificate(client kubernetes.Interface, namespace, secretName, service string) (certPEM, keyPEM []byte, err error) {
+	// another replica may store its certificate first, which is then read back. retries are bounded, in case the
+	// secret keeps changing under us.
+	backoffErr := wait.ExponentialBackoff(retry.DefaultRetry, func() (bool, error) {
+		certPEM, keyPEM, err = ensureCertificate(client, namespace, secretName, service)
+		if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
+			return false, nil
+		}
+		return true, err
+	})
+	if backoffErr == wait.ErrWaitTimeout {
+		return nil, nil, err
+	}
+	if backoffErr != nil {
+		return nil, nil, backoffErr
+	}
+	return certPEM, keyPEM, nil
+}
+
+func ensureCertificate(client kubernetes.Interface, namespace, secretName, service string) (certPEM, keyPEM []byte, err error) {
+	host := fmt.Sprintf("%s.%s.svc", service, namespace)
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if validCertificate(secret, host, time.Now()) {
+			return secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], nil
+		}
+	case errors.IsNotFound(err):
+		secret = nil
+	default:
+		return nil, nil, err
+	}
+
+	log.Infof("generating serving certificate for %s", host)
+	certPEM, keyPEM, err = cert.GenerateSelfSignedCertKey(host, nil, []string{service, service + "." + namespace})
+	if err != nil {
+		return nil, nil, err
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+
+	if secret == nil {
+		_, err = client.CoreV1().Secrets(namespace).Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		})
+	} else {
+		secret = secret.DeepCopy()
+		secret.Data = data
+		_, err = client.CoreV1().Secrets(namespace).Update(secret)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+func validCertificate(secret *corev1.Secret, host string, now time.Time) bool {
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	return leaf.VerifyHostname(host) == nil && now.Add(RenewBefore).Before(leaf.NotAfter)
+}
+
+// Certificate is the serving certificate of the webhook, which is replaced while it is served when it is renewed.
+type Certificate struct {
+	mu      sync.RWMutex
+	certPEM []byte
+	pair    *tls.Certificate
+}
+
+// Set replaces the certificate served from now on.
+func (c *Certificate) Set(certPEM, keyPEM []byte) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.certPEM = certPEM
+	c.pair = &pair
+	return nil
+}
+
+// PEM returns the current certificate chain.
+func (c *Certificate) PEM() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.certPEM
+}
+
+// GetCertificate returns the current certificate, to be used as the GetCertificate of a tls.Config.
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.pair == nil {
+		return nil, fmt.Errorf("no serving certificate")
+	}
+	return c.pair, nil
+}
+
+// InjectCABundle makes the API server trust the serving certificate, by setting the CA bundle of every webhook in
+// the named configuration. the certificate chain ends with its CA, so it is used as the bundle as is.
+func InjectCABundle(client kubernetes.Interface, configName string, certPEM []byte) error {
+	config, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get(configName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	config = config.DeepCopy()
+	changed := false
+	for i := range config.Webhooks {
+		if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, certPEM) {
+			config.Webhooks[i].ClientConfig.CABundle = certPEM
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Update(config)
+	return err
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/retry"
+)
+
+func TestEnsureCertificate(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	certPEM, keyPEM, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets("crypt-system").Get("webhook-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected certificate to be stored: %v", err)
+	}
+	if !validCertificate(secret, "crypt-webhook.crypt-system.svc", time.Now()) {
+		t.Error("expected stored certificate to be valid for the service")
+	}
+
+	// other replicas serve the stored certificate
+	again, _, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(again, certPEM) {
+		t.Error("expected stored certificate to be reused")
+	}
+
+	// a certificate about to expire is replaced
+	if validCertificate(secret, "crypt-webhook.crypt-system.svc", time.Now().Add(365*24*time.Hour-RenewBefore)) {
+		t.Error("expected certificate to be renewed before it expires")
+	}
+	if validCertificate(secret, "other.crypt-system.svc", time.Now()) {
+		t.Error("expected certificate to be invalid for another service")
+	}
+
+	secret.Data[corev1.TLSPrivateKeyKey] = []byte("invalid")
+	client.CoreV1().Secrets("crypt-system").Update(secret)
+	renewed, renewedKey, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(renewed, certPEM) || bytes.Equal(renewedKey, keyPEM) {
+		t.Error("expected invalid certificate to be replaced")
+	}
+}
+
+func TestInjectCABundle(t *testing.T) {
+	client := fake.NewSimpleClientset(&admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "crypt-controller"},
+		Webhooks:   []admissionregistrationv1beta1.Webhook{{Name: "crypts.core.bluehoodie.io"}},
+	})
+
+	if err := InjectCABundle(client, "crypt-controller", []byte("bundle")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config, _ := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("crypt-controller", metav1.GetOptions{})
+	if string(config.Webhooks[0].ClientConfig.CABundle) != "bundle" {
+		t.Errorf("expected CA bundle to be set, got %q", config.Webhooks[0].ClientConfig.CABundle)
+	}
+
+	// nothing is written when the bundle is already set
+	client.ClearActions()
+	if err := InjectCABundle(client, "crypt-controller", []byte("bundle")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("unexpected update: %+v", action)
+		}
+	}
+}
+
+func TestEnsureCertificateRetriesBounded(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	creates := 0
+	client.PrependReactor("create", "secrets", func(action core.Action) (bool, runtime.Object, error) {
+		creates++
+		return true, nil, errors.NewAlreadyExists(corev1.Resource("secrets"), "webhook-tls")
+	})
+
+	// the secret another replica created is never found
+	if _, _, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook"); !errors.IsAlreadyExists(err) {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+	if creates != retry.DefaultRetry.Steps {
+		t.Errorf("expected %d attempts, got %d", retry.DefaultRetry.Steps, creates)
+	}
+}
+
+func TestCertificateReplaced(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	certificate := &Certificate{}
+
+	if _, err := certificate.GetCertificate(nil); err == nil {
+		t.Error("expected no certificate to be served before one is set")
+	}
+
+	certPEM, keyPEM, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := certificate.Set(certPEM, keyPEM); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	served, err := certificate.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a renewed certificate is served to the next connections
+	client.CoreV1().Secrets("crypt-system").Delete("webhook-tls", nil)
+	renewed, renewedKey, err := EnsureCertificate(client, "crypt-system", "webhook-tls", "crypt-webhook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := certificate.Set(renewed, renewedKey); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := certificate.GetCertificate(nil); again == served {
+		t.Error("expected the renewed certificate to be served")
+	}
+	if !bytes.Equal(certificate.PEM(), renewed) {
+		t.Error("expected the renewed certificate chain")
+	}
+
+	if err := certificate.Set(renewed, []byte("invalid")); err == nil {
+		t.Error("expected an invalid key pair to be refused")
+	}
+}
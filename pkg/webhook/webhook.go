@@ -0,0 +1,103 @@
+// Package webhook serves the validating admission webhook of Crypts, which rejects the Crypts the controller would
+// refuse to sync.
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/validation"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	log "k8s.io/klog"
+)
+
+// ValidatePath is the path the API server sends Crypts to for validation.
+const ValidatePath = "/validate"
+
+// Handler returns the handler of the webhook.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, serveValidate)
+	return mux
+}
+
+// Serve serves the webhook over TLS on addr until stop is closed. every connection is served the current certificate.
+func Serve(addr string, certificate *Certificate, stop <-chan struct{}) error {
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   Handler(),
+		TLSConfig: &tls.Config{GetCertificate: certificate.GetCertificate},
+	}
+
+	go func() {
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+
+	err := server.ListenAndServeTLS("", "")
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func serveValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionv1beta1.AdmissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "expected an admission review request", http.StatusBadRequest)
+		return
+	}
+
+	response := validate(review.Request)
+	response.UID = review.Request.UID
+
+	review.Request = nil
+	review.Response = response
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Errorf("could not write admission review response: %v", err)
+	}
+}
+
+func validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+
+	var crypt v1alpha1.Crypt
+	if err := json.Unmarshal(req.Object.Raw, &crypt); err != nil {
+		return deny(errors.NewBadRequest(err.Error()))
+	}
+
+	if req.Operation == admissionv1beta1.Update {
+		// crypts created before the webhook was enabled can still get their finalizer added and removed
+		var old v1alpha1.Crypt
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err == nil && apiequality.Semantic.DeepEqual(old.Spec, crypt.Spec) {
+			return &admissionv1beta1.AdmissionResponse{Allowed: true}
+		}
+	}
+
+	if errs := validation.ValidateCrypt(&crypt); len(errs) > 0 {
+		return deny(errors.NewInvalid(v1alpha1.Kind("Crypt"), crypt.Name, errs))
+	}
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+func deny(err *errors.StatusError) *admissionv1beta1.AdmissionResponse {
+	status := err.Status()
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result:  &status,
+	}
+}
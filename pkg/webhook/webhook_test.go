@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluehoodie/crypt-controller/pkg/apis/crypt/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newCrypt(key string) *v1alpha1.Crypt {
+	return &v1alpha1.Crypt{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-crypt", Namespace: "default"},
+		Spec: v1alpha1.CryptSpec{
+			Secrets:           []v1alpha1.SecretDefinition{{Name: "foo", Key: key}},
+			NamespacePatterns: []v1alpha1.NamespacePattern{{Pattern: "dev-*"}},
+		},
+	}
+}
+
+func review(t *testing.T, operation admissionv1beta1.Operation, crypt, old *v1alpha1.Crypt) *admissionv1beta1.AdmissionResponse {
+	req := &admissionv1beta1.AdmissionRequest{UID: "test-uid", Operation: operation}
+	req.Object = runtime.RawExtension{Raw: mustMarshal(t, crypt)}
+	if old != nil {
+		req.OldObject = runtime.RawExtension{Raw: mustMarshal(t, old)}
+	}
+
+	body := mustMarshal(t, &admissionv1beta1.AdmissionReview{Request: req})
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+
+	var result admissionv1beta1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if result.Response == nil || result.Response.UID != req.UID {
+		t.Fatalf("expected a response to request %s, got %+v", req.UID, result.Response)
+	}
+	return result.Response
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestValidCryptAllowed(t *testing.T) {
+	if resp := review(t, admissionv1beta1.Create, newCrypt("dev/foo"), nil); !resp.Allowed {
+		t.Errorf("expected crypt to be allowed, got %+v", resp.Result)
+	}
+}
+
+func TestInvalidCryptDenied(t *testing.T) {
+	resp := review(t, admissionv1beta1.Create, newCrypt(""), nil)
+	if resp.Allowed {
+		t.Fatal("expected crypt without a key to be denied")
+	}
+	if resp.Result == nil || resp.Result.Code != http.StatusUnprocessableEntity || resp.Result.Reason != metav1.StatusReasonInvalid {
+		t.Errorf("expected an invalid status, got %+v", resp.Result)
+	}
+}
+
+func TestMetadataUpdateOfInvalidCryptAllowed(t *testing.T) {
+	old := newCrypt("")
+	finalized := old.DeepCopy()
+	finalized.Finalizers = []string{"core.bluehoodie.io/secrets"}
+
+	if resp := review(t, admissionv1beta1.Update, finalized, old); !resp.Allowed {
+		t.Errorf("expected finalizer to be added, got %+v", resp.Result)
+	}
+
+	changed := finalized.DeepCopy()
+	changed.Spec.Secrets[0].Name = "bar"
+	if resp := review(t, admissionv1beta1.Update, changed, finalized); resp.Allowed {
+		t.Error("expected spec change of invalid crypt to be denied")
+	}
+}
+
+func TestMalformedReviewRejected(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader([]byte("{"))))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}